@@ -2,6 +2,7 @@ package blooper
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -12,6 +13,7 @@ import (
 	"github.com/BlooperDB/API/api"
 	"github.com/BlooperDB/API/db"
 	"github.com/BlooperDB/API/nodes"
+	"github.com/BlooperDB/API/render"
 	"github.com/BlooperDB/API/storage"
 	"github.com/BlooperDB/API/utils"
 	"github.com/gorilla/handlers"
@@ -26,10 +28,22 @@ func Initialize() {
 	var listenPort int
 	var postgresHost string
 	var minioHost string
+	var storageBackend string
+	var storagePath string
+	var renderWorkers int
+	var renderVariants string
+	var scoreDecay float64
 
 	flag.IntVar(&listenPort, "listen-port", 8080, "sets the port to run on")
 	flag.StringVar(&postgresHost, "postgres-host", "postgres", "sets the postgres host to connect to")
 	flag.StringVar(&minioHost, "minio-host", "minio", "sets the minio host to connect to")
+	// The local and memory backends are served by this server below /storage,
+	// STORAGE_BASE_URL must point there and defaults to localhost
+	flag.StringVar(&storageBackend, "storage", "minio", "sets the storage backend (minio, local or memory), local and memory are served below /storage at STORAGE_BASE_URL (default http://localhost:<listen-port>/storage)")
+	flag.StringVar(&storagePath, "storage-path", "data", "sets the directory of the local storage backend")
+	flag.IntVar(&renderWorkers, "render-workers", 2, "sets the number of render workers, 0 leaves rendering to cmd/render")
+	flag.StringVar(&renderVariants, "render-variants", "", "sets the JSON file listing the render variants")
+	flag.Float64Var(&scoreDecay, "score-decay", 1, "sets the weight of ratings per newer revision of a blueprint, 1 disables decay")
 	flag.Parse()
 
 	firebase.InitializeApp(&firebase.Options{
@@ -38,9 +52,37 @@ func Initialize() {
 
 	utils.Initialize()
 
+	if scoreDecay <= 0 || scoreDecay > 1 {
+		log.Fatal("score-decay must be greater than 0 and at most 1")
+	}
+
+	db.ScoreDecay = scoreDecay
+
 	InitializeDB(postgresHost)
 
-	InitializeStorage(minioHost)
+	storageURL := os.Getenv("STORAGE_BASE_URL")
+
+	if storageURL == "" && storageBackend != "minio" {
+		storageURL = fmt.Sprintf("http://localhost:%d/storage", listenPort)
+	}
+
+	if err := InitializeStorage(storageBackend, minioHost, storagePath, storageURL); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := storage.MigrateRevisionStrings(); err != nil {
+		log.Fatal(err)
+	}
+
+	if renderVariants != "" {
+		if err := render.LoadVariants(renderVariants); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	render.Start(render.Options{
+		Workers: renderWorkers,
+	})
 
 	nodes.InitializeGraphs()
 
@@ -60,6 +102,14 @@ func Initialize() {
 	nodes.RegisterCommentRoutes(v1)
 	nodes.RegisterRevisionRoutes(v1)
 	nodes.RegisterTagRoutes(v1)
+	nodes.RegisterCollectionRoutes(v1)
+
+	nodes.RegisterRenderRoutes(api.RawRouteHandler(router, "/v1"))
+
+	// MinIO serves its buckets itself
+	if storageBackend != "minio" {
+		router.PathPrefix("/storage/").Handler(http.StripPrefix("/storage", storage.Handler()))
+	}
 
 	router.HandleFunc("/v2", func(w http.ResponseWriter, r *http.Request) {
 		ctx := context.WithValue(r.Context(), "blooper-token", r.Header.Get("blooper-token"))
@@ -103,17 +153,27 @@ func InitializeDB(postgresHost string) {
 	db.Initialize(connection)
 }
 
-func InitializeStorage(minioHost string) {
-	var (
-		minio_access_key = os.Getenv("MINIO_ACCESS_KEY")
-		minio_secret_key = os.Getenv("MINIO_SECRET_KEY")
-	)
+// InitializeStorage sets up the storage backend, objects are publicly
+// available below publicURL
+func InitializeStorage(backend string, minioHost string, storagePath string, publicURL string) error {
+	switch backend {
+	case "minio":
+		var (
+			minio_access_key = os.Getenv("MINIO_ACCESS_KEY")
+			minio_secret_key = os.Getenv("MINIO_SECRET_KEY")
+		)
+
+		minioClient, err := minio.New(minioHost+":9000", minio_access_key, minio_secret_key, false)
+		if err != nil {
+			return err
+		}
 
-	minioClient, err := minio.New(minioHost+":9000", minio_access_key, minio_secret_key, false)
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return storage.Initialize(storage.NewMinioBackend(minioClient, publicURL))
+	case "local":
+		return storage.Initialize(storage.NewLocalBackend(storagePath, publicURL))
+	case "memory":
+		return storage.Initialize(storage.NewMemoryBackend(publicURL))
 	}
 
-	storage.Initialize(minioClient, os.Getenv("STORAGE_BASE_URL"))
+	return errors.New("unknown storage backend " + backend)
 }
@@ -82,6 +82,34 @@ func RouteHandler(router *mux.Router, prefix string) RegisterRoute {
 	}
 }
 
+// RawHandle writes the response itself, only errors are sent as usual
+type RawHandle func(http.ResponseWriter, *http.Request) *utils.ErrorResponse
+
+type RegisterRawRoute func(method string, path string, handle RawHandle)
+
+func RawRouteHandler(router *mux.Router, prefix string) RegisterRawRoute {
+	return func(method string, path string, handle RawHandle) {
+		route := router.NewRoute()
+		route.PathPrefix(prefix)
+		route.Path(path)
+		route.Methods(method)
+		route.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := handle(w, r)
+
+			if err == nil {
+				return
+			}
+
+			ProcessResponse(func(http.ResponseWriter, *http.Request) utils.GenericResponse {
+				return utils.GenericResponse{
+					Success: false,
+					Error:   err,
+				}
+			}).ServeHTTP(w, r)
+		}))
+	}
+}
+
 func LoggerHandler(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		logger := makeLogger(w)
@@ -0,0 +1,142 @@
+package blueprint
+
+const (
+	KindBlueprint             = "blueprint"
+	KindBlueprintBook         = "blueprint_book"
+	KindDeconstructionPlanner = "deconstruction_planner"
+	KindUpgradePlanner        = "upgrade_planner"
+)
+
+// Container is the top level object of a decoded blueprint string, exactly
+// one of its fields is set
+type Container struct {
+	Blueprint             *Blueprint             `json:"blueprint,omitempty"`
+	BlueprintBook         *Book                  `json:"blueprint_book,omitempty"`
+	DeconstructionPlanner *DeconstructionPlanner `json:"deconstruction_planner,omitempty"`
+	UpgradePlanner        *UpgradePlanner        `json:"upgrade_planner,omitempty"`
+}
+
+func (c *Container) Kind() string {
+	switch {
+	case c.Blueprint != nil:
+		return KindBlueprint
+	case c.BlueprintBook != nil:
+		return KindBlueprintBook
+	case c.DeconstructionPlanner != nil:
+		return KindDeconstructionPlanner
+	case c.UpgradePlanner != nil:
+		return KindUpgradePlanner
+	}
+	return ""
+}
+
+func (c *Container) Label() string {
+	switch {
+	case c.Blueprint != nil:
+		return c.Blueprint.Label
+	case c.BlueprintBook != nil:
+		return c.BlueprintBook.Label
+	case c.DeconstructionPlanner != nil:
+		return c.DeconstructionPlanner.Label
+	case c.UpgradePlanner != nil:
+		return c.UpgradePlanner.Label
+	}
+	return ""
+}
+
+func (c *Container) Description() string {
+	switch {
+	case c.Blueprint != nil:
+		return c.Blueprint.Description
+	case c.BlueprintBook != nil:
+		return c.BlueprintBook.Description
+	case c.DeconstructionPlanner != nil:
+		return c.DeconstructionPlanner.Settings.Description
+	case c.UpgradePlanner != nil:
+		return c.UpgradePlanner.Settings.Description
+	}
+	return ""
+}
+
+func (c *Container) Version() Version {
+	switch {
+	case c.Blueprint != nil:
+		return c.Blueprint.Version
+	case c.BlueprintBook != nil:
+		return c.BlueprintBook.Version
+	case c.DeconstructionPlanner != nil:
+		return c.DeconstructionPlanner.Version
+	case c.UpgradePlanner != nil:
+		return c.UpgradePlanner.Version
+	}
+	return 0
+}
+
+// Renderable reports whether the container holds anything that can be drawn
+func (c *Container) Renderable() bool {
+	return c.Blueprint != nil || c.BlueprintBook != nil
+}
+
+type Blueprint struct {
+	Item        string     `json:"item"`
+	Label       string     `json:"label,omitempty"`
+	LabelColor  *Color     `json:"label_color,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Icons       []Icon     `json:"icons,omitempty"`
+	Entities    []Entity   `json:"entities,omitempty"`
+	Tiles       []Tile     `json:"tiles,omitempty"`
+	Schedules   []Schedule `json:"schedules,omitempty"`
+	Version     Version    `json:"version"`
+}
+
+type Icon struct {
+	Index  int      `json:"index"`
+	Signal SignalID `json:"signal"`
+}
+
+type SignalID struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type Position struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type Color struct {
+	R float64 `json:"r"`
+	G float64 `json:"g"`
+	B float64 `json:"b"`
+	A float64 `json:"a"`
+}
+
+type Tile struct {
+	Name     string   `json:"name"`
+	Position Position `json:"position"`
+}
+
+type Schedule struct {
+	Schedule    []ScheduleRecord `json:"schedule"`
+	Locomotives []int            `json:"locomotives"`
+}
+
+type ScheduleRecord struct {
+	Station        string          `json:"station"`
+	WaitConditions []WaitCondition `json:"wait_conditions,omitempty"`
+	Temporary      bool            `json:"temporary,omitempty"`
+}
+
+type WaitCondition struct {
+	Type        string     `json:"type"`
+	CompareType string     `json:"compare_type"`
+	Ticks       int        `json:"ticks,omitempty"`
+	Condition   *Condition `json:"condition,omitempty"`
+}
+
+type Condition struct {
+	Comparator   string    `json:"comparator,omitempty"`
+	FirstSignal  *SignalID `json:"first_signal,omitempty"`
+	SecondSignal *SignalID `json:"second_signal,omitempty"`
+	Constant     *int      `json:"constant,omitempty"`
+}
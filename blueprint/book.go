@@ -0,0 +1,54 @@
+package blueprint
+
+type Book struct {
+	Item        string      `json:"item"`
+	Label       string      `json:"label,omitempty"`
+	LabelColor  *Color      `json:"label_color,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Icons       []Icon      `json:"icons,omitempty"`
+	Blueprints  []BookEntry `json:"blueprints"`
+	ActiveIndex int         `json:"active_index"`
+	Version     Version     `json:"version"`
+}
+
+// BookEntry is a single slot of a book, it may hold any kind including
+// another book
+type BookEntry struct {
+	Index int `json:"index"`
+	Container
+}
+
+func (b *Book) Child(index int) *BookEntry {
+	for i := range b.Blueprints {
+		if b.Blueprints[i].Index == index {
+			return &b.Blueprints[i]
+		}
+	}
+	return nil
+}
+
+// NewBook puts the containers into a new book in the given order, the book
+// has the version of its newest entry
+func NewBook(label string, description string, containers []*Container) *Container {
+	book := &Book{
+		Item:        "blueprint-book",
+		Label:       label,
+		Description: description,
+		Blueprints:  make([]BookEntry, len(containers)),
+	}
+
+	for i, container := range containers {
+		book.Blueprints[i] = BookEntry{
+			Index:     i,
+			Container: *container,
+		}
+
+		if version := container.Version(); version > book.Version {
+			book.Version = version
+		}
+	}
+
+	return &Container{
+		BlueprintBook: book,
+	}
+}
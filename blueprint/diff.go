@@ -0,0 +1,434 @@
+package blueprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Fields of entities compared by Diff
+const (
+	DiffDirection = "direction"
+	DiffRecipe    = "recipe"
+	DiffModules   = "modules"
+	DiffWires     = "wires"
+	DiffSettings  = "settings"
+)
+
+// Diff holds the entity level changes between two blueprints
+type Diff struct {
+	Added   []DiffEntity `json:"added"`
+	Removed []DiffEntity `json:"removed"`
+	Moved   []DiffMove   `json:"moved"`
+	Changed []DiffChange `json:"changed"`
+
+	TilesAdded   int `json:"tiles-added"`
+	TilesRemoved int `json:"tiles-removed"`
+}
+
+type DiffEntity struct {
+	Name     string   `json:"name"`
+	Position Position `json:"position"`
+}
+
+// DiffMove is an entity placed elsewhere with the same configuration
+type DiffMove struct {
+	Name string   `json:"name"`
+	From Position `json:"from"`
+	To   Position `json:"to"`
+}
+
+// DiffChange is an entity that kept its place but was reconfigured
+type DiffChange struct {
+	Name     string        `json:"name"`
+	Position Position      `json:"position"`
+	Changes  []FieldChange `json:"changes"`
+}
+
+// FieldChange describes the old and new value of a field, wire changes list
+// the removed wires as From and the added ones as To
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Moved) == 0 && len(d.Changed) == 0 &&
+		d.TilesAdded == 0 && d.TilesRemoved == 0
+}
+
+// DiffBlueprints compares two blueprints, nil blueprints are empty. Entities
+// are matched by name and position as entity numbers are not stable between
+// exports, unmatched entities of the same name and configuration are moves.
+func DiffBlueprints(from *Blueprint, to *Blueprint) *Diff {
+	if from == nil {
+		from = &Blueprint{}
+	}
+
+	if to == nil {
+		to = &Blueprint{}
+	}
+
+	diff := &Diff{
+		Added:   []DiffEntity{},
+		Removed: []DiffEntity{},
+		Moved:   []DiffMove{},
+		Changed: []DiffChange{},
+	}
+
+	// Index of the matching entity in the other blueprint, -1 if unmatched
+	fromMatch := make([]int, len(from.Entities))
+	toMatch := make([]int, len(to.Entities))
+
+	placed := make(map[string][]int)
+
+	for i, entity := range from.Entities {
+		fromMatch[i] = -1
+		key := placementKey(entity)
+		placed[key] = append(placed[key], i)
+	}
+
+	for j, entity := range to.Entities {
+		toMatch[j] = -1
+		key := placementKey(entity)
+
+		if candidates := placed[key]; len(candidates) > 0 {
+			fromMatch[candidates[0]] = j
+			toMatch[j] = candidates[0]
+			placed[key] = candidates[1:]
+		}
+	}
+
+	// Remaining entities with equal configuration are matched to the closest
+	// one as moves
+	unplaced := make(map[string][]int)
+
+	for i, entity := range from.Entities {
+		if fromMatch[i] == -1 {
+			key := configurationKey(entity)
+			unplaced[key] = append(unplaced[key], i)
+		}
+	}
+
+	for j, entity := range to.Entities {
+		if toMatch[j] != -1 {
+			continue
+		}
+
+		key := configurationKey(entity)
+		candidates := unplaced[key]
+
+		if len(candidates) == 0 {
+			diff.Added = append(diff.Added, DiffEntity{entity.Name, entity.Position})
+			continue
+		}
+
+		closest := 0
+
+		for c := range candidates {
+			if distance(from.Entities[candidates[c]].Position, entity.Position) < distance(from.Entities[candidates[closest]].Position, entity.Position) {
+				closest = c
+			}
+		}
+
+		i := candidates[closest]
+		fromMatch[i] = j
+		toMatch[j] = i
+		unplaced[key] = append(candidates[:closest:closest], candidates[closest+1:]...)
+
+		diff.Moved = append(diff.Moved, DiffMove{entity.Name, from.Entities[i].Position, entity.Position})
+	}
+
+	for i, entity := range from.Entities {
+		if fromMatch[i] == -1 {
+			diff.Removed = append(diff.Removed, DiffEntity{entity.Name, entity.Position})
+		}
+	}
+
+	// Wires are compared by the matched entities they connect
+	fromWires := entityWires(from, func(i int) int { return i })
+	toWires := entityWires(to, func(j int) int {
+		if toMatch[j] != -1 {
+			return toMatch[j]
+		}
+		return len(from.Entities) + j
+	})
+
+	for i, j := range fromMatch {
+		if j == -1 || from.Entities[i].Position != to.Entities[j].Position {
+			continue
+		}
+
+		changes := compareEntities(from.Entities[i], to.Entities[j])
+
+		if removed, added := compareWires(fromWires[i], toWires[j]); removed != "" || added != "" {
+			changes = append(changes, FieldChange{DiffWires, removed, added})
+		}
+
+		if len(changes) > 0 {
+			diff.Changed = append(diff.Changed, DiffChange{to.Entities[j].Name, to.Entities[j].Position, changes})
+		}
+	}
+
+	diff.TilesAdded, diff.TilesRemoved = compareTiles(from.Tiles, to.Tiles)
+
+	sortEntities(diff.Added)
+	sortEntities(diff.Removed)
+
+	sort.SliceStable(diff.Moved, func(a, b int) bool {
+		return positionLess(diff.Moved[a].To, diff.Moved[b].To)
+	})
+
+	sort.SliceStable(diff.Changed, func(a, b int) bool {
+		return positionLess(diff.Changed[a].Position, diff.Changed[b].Position)
+	})
+
+	return diff
+}
+
+func placementKey(entity Entity) string {
+	return entity.Name + "@" + formatPosition(entity.Position)
+}
+
+// configurationKey identifies the configuration of an entity regardless of
+// its place and wires
+func configurationKey(entity Entity) string {
+	return entity.Name + "|" + strconv.Itoa(entity.Direction) + "|" + entity.Recipe + "|" + formatModules(entity.Items) + "|" + entitySettings(entity)
+}
+
+func compareEntities(from Entity, to Entity) []FieldChange {
+	var changes []FieldChange
+
+	if from.Direction != to.Direction {
+		changes = append(changes, FieldChange{DiffDirection, formatDirection(from.Direction), formatDirection(to.Direction)})
+	}
+
+	if from.Recipe != to.Recipe {
+		changes = append(changes, FieldChange{DiffRecipe, from.Recipe, to.Recipe})
+	}
+
+	if fromModules, toModules := formatModules(from.Items), formatModules(to.Items); fromModules != toModules {
+		changes = append(changes, FieldChange{DiffModules, fromModules, toModules})
+	}
+
+	if fromSettings, toSettings := entitySettings(from), entitySettings(to); fromSettings != toSettings {
+		changes = append(changes, FieldChange{DiffSettings, fromSettings, toSettings})
+	}
+
+	return changes
+}
+
+// Entity fields compared on their own or not part of the configuration
+var diffIgnoredFields = []string{"entity_number", "name", "position", "direction", "recipe", "items", "connections", "neighbours"}
+
+// entitySettings is the JSON of everything not compared on its own
+func entitySettings(entity Entity) string {
+	data, err := json.Marshal(entity)
+
+	if err != nil {
+		return ""
+	}
+
+	var fields map[string]json.RawMessage
+
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return ""
+	}
+
+	for _, field := range diffIgnoredFields {
+		delete(fields, field)
+	}
+
+	if len(fields) == 0 {
+		return ""
+	}
+
+	// Keys of maps are marshalled sorted
+	data, err = json.Marshal(fields)
+
+	if err != nil {
+		return ""
+	}
+
+	return string(data)
+}
+
+// wire is a connection to another entity, described with the position of
+// that entity in its own blueprint
+type wire struct {
+	key         string
+	description string
+}
+
+// entityWires lists the wires of every entity, id maps entity indexes to the
+// identity used to compare them
+func entityWires(bp *Blueprint, id func(int) int) [][]wire {
+	indexes := make(map[int]int, len(bp.Entities))
+
+	for i, entity := range bp.Entities {
+		indexes[entity.EntityNumber] = i
+	}
+
+	wires := make([][]wire, len(bp.Entities))
+
+	add := func(i int, kind string, target int, circuit int) {
+		t, ok := indexes[target]
+
+		if !ok {
+			return
+		}
+
+		other := bp.Entities[t]
+		description := kind + " to " + other.Name + " at " + formatPosition(other.Position)
+
+		if circuit != 0 {
+			description += " (" + strconv.Itoa(circuit) + ")"
+		}
+
+		wires[i] = append(wires[i], wire{
+			key:         kind + " " + strconv.Itoa(id(t)) + " " + strconv.Itoa(circuit),
+			description: description,
+		})
+	}
+
+	for i, entity := range bp.Entities {
+		for _, neighbour := range entity.Neighbours {
+			add(i, "copper", neighbour, 0)
+		}
+
+		if entity.Connections == nil {
+			continue
+		}
+
+		for point, cp := range entity.Connections.Points {
+			for _, w := range cp.Red {
+				add(i, "red "+strconv.Itoa(point), w.EntityID, w.CircuitID)
+			}
+
+			for _, w := range cp.Green {
+				add(i, "green "+strconv.Itoa(point), w.EntityID, w.CircuitID)
+			}
+		}
+
+		for point, copper := range entity.Connections.Copper {
+			for _, w := range copper {
+				add(i, "copper "+point, w.EntityID, w.WireID)
+			}
+		}
+	}
+
+	return wires
+}
+
+// compareWires returns the removed and added wires
+func compareWires(from []wire, to []wire) (string, string) {
+	difference := func(a []wire, b []wire) string {
+		keys := make(map[string]bool, len(b))
+
+		for _, w := range b {
+			keys[w.key] = true
+		}
+
+		var descriptions []string
+
+		for _, w := range a {
+			if !keys[w.key] {
+				descriptions = append(descriptions, w.description)
+			}
+		}
+
+		sort.Strings(descriptions)
+
+		return strings.Join(descriptions, ", ")
+	}
+
+	return difference(from, to), difference(to, from)
+}
+
+func compareTiles(from []Tile, to []Tile) (int, int) {
+	tiles := make(map[string]int, len(from))
+
+	for _, tile := range from {
+		tiles[tile.Name+"@"+formatPosition(tile.Position)]++
+	}
+
+	added := 0
+
+	for _, tile := range to {
+		key := tile.Name + "@" + formatPosition(tile.Position)
+
+		if tiles[key] > 0 {
+			tiles[key]--
+		} else {
+			added++
+		}
+	}
+
+	removed := 0
+
+	for _, count := range tiles {
+		removed += count
+	}
+
+	return added, removed
+}
+
+var directionNames = map[int]string{
+	0: "north",
+	2: "east",
+	4: "south",
+	6: "west",
+}
+
+func formatDirection(direction int) string {
+	if name, ok := directionNames[direction]; ok {
+		return name
+	}
+	return strconv.Itoa(direction)
+}
+
+// formatModules lists the inserted items sorted by name
+func formatModules(items map[string]int) string {
+	names := make([]string, 0, len(items))
+
+	for name := range items {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for i, name := range names {
+		names[i] = fmt.Sprintf("%s x%d", name, items[name])
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func formatPosition(position Position) string {
+	return strconv.FormatFloat(position.X, 'f', -1, 64) + "," + strconv.FormatFloat(position.Y, 'f', -1, 64)
+}
+
+func distance(a Position, b Position) float64 {
+	return math.Hypot(a.X-b.X, a.Y-b.Y)
+}
+
+// positionLess orders top to bottom, then left to right
+func positionLess(a Position, b Position) bool {
+	if a.Y != b.Y {
+		return a.Y < b.Y
+	}
+	return a.X < b.X
+}
+
+func sortEntities(entities []DiffEntity) {
+	sort.SliceStable(entities, func(a, b int) bool {
+		if entities[a].Position != entities[b].Position {
+			return positionLess(entities[a].Position, entities[b].Position)
+		}
+		return entities[a].Name < entities[b].Name
+	})
+}
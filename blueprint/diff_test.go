@@ -0,0 +1,137 @@
+package blueprint
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffBlueprints(t *testing.T) {
+	belt := func(x, y float64, direction int) Entity {
+		return Entity{Name: "transport-belt", Position: Position{x, y}, Direction: direction}
+	}
+
+	assembler := func(x, y float64, recipe string) Entity {
+		return Entity{Name: "assembling-machine-2", Position: Position{x, y}, Recipe: recipe}
+	}
+
+	tests := []struct {
+		name string
+		from *Blueprint
+		to   *Blueprint
+		want Diff
+	}{
+		{
+			name: "identical",
+			from: &Blueprint{Entities: []Entity{belt(0.5, 0.5, 0), assembler(3.5, 3.5, "")}},
+			to:   &Blueprint{Entities: []Entity{assembler(3.5, 3.5, ""), belt(0.5, 0.5, 0)}},
+		},
+		{
+			name: "added",
+			from: &Blueprint{Entities: []Entity{belt(0.5, 0.5, 0)}},
+			to:   &Blueprint{Entities: []Entity{belt(0.5, 0.5, 0), assembler(3.5, 3.5, "")}},
+			want: Diff{Added: []DiffEntity{{"assembling-machine-2", Position{3.5, 3.5}}}},
+		},
+		{
+			name: "removed",
+			from: &Blueprint{Entities: []Entity{belt(0.5, 0.5, 0), belt(1.5, 0.5, 0)}},
+			to:   &Blueprint{Entities: []Entity{belt(1.5, 0.5, 0)}},
+			want: Diff{Removed: []DiffEntity{{"transport-belt", Position{0.5, 0.5}}}},
+		},
+		{
+			name: "nil blueprint",
+			to:   &Blueprint{Entities: []Entity{belt(1.5, 0.5, 0), belt(0.5, 0.5, 0)}},
+			want: Diff{Added: []DiffEntity{
+				{"transport-belt", Position{0.5, 0.5}},
+				{"transport-belt", Position{1.5, 0.5}},
+			}},
+		},
+		{
+			name: "moved",
+			from: &Blueprint{Entities: []Entity{assembler(3.5, 3.5, "iron-gear-wheel")}},
+			to:   &Blueprint{Entities: []Entity{assembler(6.5, 3.5, "iron-gear-wheel")}},
+			want: Diff{Moved: []DiffMove{{"assembling-machine-2", Position{3.5, 3.5}, Position{6.5, 3.5}}}},
+		},
+		{
+			name: "moved to the closest",
+			from: &Blueprint{Entities: []Entity{belt(0.5, 0.5, 2), belt(20.5, 0.5, 2)}},
+			to:   &Blueprint{Entities: []Entity{belt(19.5, 0.5, 2), belt(1.5, 0.5, 2)}},
+			want: Diff{Moved: []DiffMove{
+				{"transport-belt", Position{0.5, 0.5}, Position{1.5, 0.5}},
+				{"transport-belt", Position{20.5, 0.5}, Position{19.5, 0.5}},
+			}},
+		},
+		{
+			name: "moved and reconfigured",
+			from: &Blueprint{Entities: []Entity{assembler(3.5, 3.5, "iron-gear-wheel")}},
+			to:   &Blueprint{Entities: []Entity{assembler(6.5, 3.5, "pipe")}},
+			want: Diff{
+				Added:   []DiffEntity{{"assembling-machine-2", Position{6.5, 3.5}}},
+				Removed: []DiffEntity{{"assembling-machine-2", Position{3.5, 3.5}}},
+			},
+		},
+		{
+			name: "rotated",
+			from: &Blueprint{Entities: []Entity{belt(0.5, 0.5, 0)}},
+			to:   &Blueprint{Entities: []Entity{belt(0.5, 0.5, 2)}},
+			want: Diff{Changed: []DiffChange{{"transport-belt", Position{0.5, 0.5}, []FieldChange{{DiffDirection, "north", "east"}}}}},
+		},
+		{
+			name: "recipe and modules",
+			from: &Blueprint{Entities: []Entity{assembler(3.5, 3.5, "")}},
+			to: &Blueprint{Entities: []Entity{{
+				Name:     "assembling-machine-2",
+				Position: Position{3.5, 3.5},
+				Recipe:   "pipe",
+				Items:    map[string]int{"speed-module": 2},
+			}}},
+			want: Diff{Changed: []DiffChange{{"assembling-machine-2", Position{3.5, 3.5}, []FieldChange{
+				{DiffRecipe, "", "pipe"},
+				{DiffModules, "", "speed-module x2"},
+			}}}},
+		},
+		{
+			name: "wires",
+			from: &Blueprint{Entities: []Entity{
+				{EntityNumber: 1, Name: "small-electric-pole", Position: Position{0.5, 0.5}},
+				{EntityNumber: 2, Name: "small-electric-pole", Position: Position{5.5, 0.5}},
+			}},
+			to: &Blueprint{Entities: []Entity{
+				{EntityNumber: 7, Name: "small-electric-pole", Position: Position{5.5, 0.5}, Neighbours: []int{8}},
+				{EntityNumber: 8, Name: "small-electric-pole", Position: Position{0.5, 0.5}, Neighbours: []int{7}},
+			}},
+			want: Diff{Changed: []DiffChange{
+				{"small-electric-pole", Position{0.5, 0.5}, []FieldChange{{DiffWires, "", "copper to small-electric-pole at 5.5,0.5"}}},
+				{"small-electric-pole", Position{5.5, 0.5}, []FieldChange{{DiffWires, "", "copper to small-electric-pole at 0.5,0.5"}}},
+			}},
+		},
+		{
+			name: "tiles",
+			from: &Blueprint{Tiles: []Tile{{"stone-path", Position{0, 0}}, {"stone-path", Position{1, 0}}}},
+			to:   &Blueprint{Tiles: []Tile{{"stone-path", Position{1, 0}}, {"concrete", Position{0, 0}}, {"concrete", Position{2, 0}}}},
+			want: Diff{TilesAdded: 2, TilesRemoved: 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want := test.want
+
+			// DiffBlueprints never returns nil lists
+			for _, list := range []interface{}{&want.Added, &want.Removed, &want.Moved, &want.Changed} {
+				if v := reflect.ValueOf(list).Elem(); v.IsNil() {
+					v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+				}
+			}
+
+			diff := DiffBlueprints(test.from, test.to)
+
+			if !reflect.DeepEqual(*diff, want) {
+				t.Errorf("got %+v, want %+v", *diff, want)
+			}
+
+			if diff.Empty() != reflect.DeepEqual(test.want, Diff{}) {
+				t.Errorf("Empty: got %v", diff.Empty())
+			}
+		})
+	}
+}
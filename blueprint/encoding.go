@@ -0,0 +1,68 @@
+package blueprint
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// StringVersion is the version byte prefixed to every encoded blueprint string
+const StringVersion = '0'
+
+var ErrInvalidString = errors.New("Not valid blueprint string")
+
+func Decode(s string) (*Container, error) {
+	s = strings.TrimSpace(s)
+
+	if len(s) < 2 || s[0] != StringVersion {
+		return nil, ErrInvalidString
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(s[1:])
+	if err != nil {
+		return nil, ErrInvalidString
+	}
+
+	r, err := zlib.NewReader(bytes.NewReader(decoded))
+	if err != nil {
+		return nil, ErrInvalidString
+	}
+	defer r.Close()
+
+	var container Container
+	if err := json.NewDecoder(r).Decode(&container); err != nil {
+		return nil, ErrInvalidString
+	}
+
+	if container.Kind() == "" {
+		return nil, ErrInvalidString
+	}
+
+	return &container, nil
+}
+
+func Encode(container *Container) (string, error) {
+	js, err := json.Marshal(container)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	w, err := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := w.Write(js); err != nil {
+		return "", err
+	}
+
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return string(StringVersion) + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,93 @@
+package blueprint
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+// compress encodes raw JSON the way the game does, without validating it
+func compress(version string, js string) string {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(js))
+	w.Close()
+	return version + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestEncodeDecode(t *testing.T) {
+	belt := &Container{Blueprint: &Blueprint{
+		Item:  "blueprint",
+		Label: "Belts",
+		Entities: []Entity{
+			{EntityNumber: 1, Name: "transport-belt", Position: Position{0.5, 0.5}},
+			{EntityNumber: 2, Name: "transport-belt", Position: Position{1.5, 0.5}, Direction: 2},
+		},
+		Tiles:   []Tile{{Name: "stone-path", Position: Position{0, 0}}},
+		Version: NewVersion(0, 16, 51, 0),
+	}}
+
+	tests := []struct {
+		name      string
+		container *Container
+	}{
+		{"blueprint", belt},
+		{"book", NewBook("Book", "Two entries", []*Container{belt, belt})},
+		{"deconstruction planner", &Container{DeconstructionPlanner: &DeconstructionPlanner{Item: "deconstruction-planner", Label: "Trees"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := Encode(test.container)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			if s[0] != StringVersion {
+				t.Errorf("Encode: got version byte %q, want %q", s[0], StringVersion)
+			}
+
+			decoded, err := Decode("  " + s + "\n")
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+
+			if !reflect.DeepEqual(decoded, test.container) {
+				t.Errorf("Decode: got %+v, want %+v", decoded, test.container)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	valid := compress("0", `{"blueprint":{"item":"blueprint"}}`)
+
+	tests := []struct {
+		name string
+		s    string
+	}{
+		{"empty", ""},
+		{"version only", "0"},
+		{"unknown version", "1" + valid[1:]},
+		{"missing version", valid[1:]},
+		{"not base64", "0!!!!"},
+		{"not zlib", "0" + base64.StdEncoding.EncodeToString([]byte("blueprint"))},
+		{"not json", compress("0", "blueprint")},
+		{"no content", compress("0", "{}")},
+		{"unknown kind", compress("0", `{"selection_tool":{"item":"selection-tool"}}`)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Decode(test.s); err != ErrInvalidString {
+				t.Errorf("Decode(%q): got error %v, want %v", test.s, err, ErrInvalidString)
+			}
+		})
+	}
+
+	if _, err := Decode(valid); err != nil {
+		t.Errorf("Decode(%q): %v", valid, err)
+	}
+}
@@ -0,0 +1,119 @@
+package blueprint
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+type Entity struct {
+	EntityNumber       int              `json:"entity_number"`
+	Name               string           `json:"name"`
+	Position           Position         `json:"position"`
+	Direction          int              `json:"direction,omitempty"`
+	Orientation        float64          `json:"orientation,omitempty"`
+	Connections        *Connections     `json:"connections,omitempty"`
+	Neighbours         []int            `json:"neighbours,omitempty"`
+	ControlBehavior    json.RawMessage  `json:"control_behavior,omitempty"`
+	Items              map[string]int   `json:"items,omitempty"`
+	Recipe             string           `json:"recipe,omitempty"`
+	Bar                *int             `json:"bar,omitempty"`
+	Type               string           `json:"type,omitempty"`
+	InputPriority      string           `json:"input_priority,omitempty"`
+	OutputPriority     string           `json:"output_priority,omitempty"`
+	Filter             string           `json:"filter,omitempty"`
+	Filters            []ItemFilter     `json:"filters,omitempty"`
+	FilterMode         string           `json:"filter_mode,omitempty"`
+	OverrideStackSize  int              `json:"override_stack_size,omitempty"`
+	DropPosition       *Position        `json:"drop_position,omitempty"`
+	PickupPosition     *Position        `json:"pickup_position,omitempty"`
+	RequestFilters     []LogisticFilter `json:"request_filters,omitempty"`
+	RequestFromBuffers bool             `json:"request_from_buffers,omitempty"`
+	Parameters         json.RawMessage  `json:"parameters,omitempty"`
+	AlertParameters    json.RawMessage  `json:"alert_parameters,omitempty"`
+	AutoLaunch         bool             `json:"auto_launch,omitempty"`
+	Variation          int              `json:"variation,omitempty"`
+	Color              *Color           `json:"color,omitempty"`
+	Station            string           `json:"station,omitempty"`
+	ManualTrainsLimit  *int             `json:"manual_trains_limit,omitempty"`
+	SwitchState        *bool            `json:"switch_state,omitempty"`
+	InfinitySettings   json.RawMessage  `json:"infinity_settings,omitempty"`
+	Tags               json.RawMessage  `json:"tags,omitempty"`
+}
+
+type ItemFilter struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+}
+
+type LogisticFilter struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Connections holds the circuit wires of an entity, keyed by connection point
+// ("1", "2"), and the copper wires of power switches ("Cu0", "Cu1")
+type Connections struct {
+	Points map[int]ConnectionPoint
+	Copper map[string][]Wire
+}
+
+type ConnectionPoint struct {
+	Red   []Wire `json:"red,omitempty"`
+	Green []Wire `json:"green,omitempty"`
+}
+
+type Wire struct {
+	EntityID  int `json:"entity_id"`
+	CircuitID int `json:"circuit_id,omitempty"`
+	WireID    int `json:"wire_id,omitempty"`
+}
+
+func (c *Connections) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	c.Points = make(map[int]ConnectionPoint)
+	c.Copper = make(map[string][]Wire)
+
+	for key, value := range raw {
+		if strings.HasPrefix(key, "Cu") {
+			var wires []Wire
+			if err := json.Unmarshal(value, &wires); err != nil {
+				return err
+			}
+			c.Copper[key] = wires
+			continue
+		}
+
+		point, err := strconv.Atoi(key)
+		if err != nil {
+			return err
+		}
+
+		var cp ConnectionPoint
+		if err := json.Unmarshal(value, &cp); err != nil {
+			return err
+		}
+		c.Points[point] = cp
+	}
+
+	return nil
+}
+
+func (c Connections) MarshalJSON() ([]byte, error) {
+	out := make(map[string]interface{}, len(c.Points)+len(c.Copper))
+
+	for point, cp := range c.Points {
+		out[strconv.Itoa(point)] = cp
+	}
+
+	for key, wires := range c.Copper {
+		out[key] = wires
+	}
+
+	return json.Marshal(out)
+}
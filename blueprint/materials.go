@@ -0,0 +1,96 @@
+package blueprint
+
+// Materials is the bill of materials needed to build a blueprint
+type Materials struct {
+	// Placed entities by entity name
+	Entities map[string]int
+
+	// Placed tiles by tile name
+	Tiles map[string]int
+
+	// Items to craft, including modules and other items inserted into entities
+	Items map[string]int
+
+	// Items broken down into raw resources
+	Raw map[string]float64
+}
+
+func NewMaterials() *Materials {
+	return &Materials{
+		Entities: make(map[string]int),
+		Tiles:    make(map[string]int),
+		Items:    make(map[string]int),
+		Raw:      make(map[string]float64),
+	}
+}
+
+// Materials of the container, books are summed over all their entries
+func (c *Container) Materials() *Materials {
+	m := NewMaterials()
+	c.addMaterials(m)
+	return m
+}
+
+func (b *Blueprint) Materials() *Materials {
+	m := NewMaterials()
+	b.addMaterials(m)
+	return m
+}
+
+func (c *Container) addMaterials(m *Materials) {
+	if c.Blueprint != nil {
+		c.Blueprint.addMaterials(m)
+	}
+
+	if c.BlueprintBook != nil {
+		for i := range c.BlueprintBook.Blueprints {
+			c.BlueprintBook.Blueprints[i].addMaterials(m)
+		}
+	}
+}
+
+func (b *Blueprint) addMaterials(m *Materials) {
+	for _, entity := range b.Entities {
+		m.Entities[entity.Name]++
+
+		if items, ok := entityItems[entity.Name]; ok {
+			for item, amount := range items {
+				m.addItem(item, amount)
+			}
+		} else {
+			m.addItem(entity.Name, 1)
+		}
+
+		for item, count := range entity.Items {
+			m.addItem(item, count)
+		}
+	}
+
+	for _, tile := range b.Tiles {
+		m.Tiles[tile.Name]++
+
+		if item, ok := tileItems[tile.Name]; ok {
+			m.addItem(item, 1)
+		} else {
+			m.addItem(tile.Name, 1)
+		}
+	}
+}
+
+func (m *Materials) addItem(item string, count int) {
+	m.Items[item] += count
+	addRaw(m.Raw, item, float64(count))
+}
+
+func addRaw(raw map[string]float64, item string, amount float64) {
+	ingredients, ok := recipes[item]
+
+	if !ok {
+		raw[item] += amount
+		return
+	}
+
+	for ingredient, count := range ingredients {
+		addRaw(raw, ingredient, count*amount)
+	}
+}
@@ -0,0 +1,123 @@
+package blueprint
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMaterials(t *testing.T) {
+	tests := []struct {
+		name      string
+		blueprint Blueprint
+		items     map[string]int
+		raw       map[string]float64
+	}{
+		{
+			name:  "empty",
+			items: map[string]int{},
+			raw:   map[string]float64{},
+		},
+		{
+			name:      "intermediate ingredients",
+			blueprint: Blueprint{Entities: []Entity{{Name: "pipe-to-ground"}, {Name: "pipe-to-ground"}}},
+			items:     map[string]int{"pipe-to-ground": 2},
+			raw:       map[string]float64{"iron-ore": 15},
+		},
+		{
+			name:      "several raw resources",
+			blueprint: Blueprint{Entities: []Entity{{Name: "medium-electric-pole"}}},
+			items:     map[string]int{"medium-electric-pole": 1},
+			raw:       map[string]float64{"iron-ore": 12, "copper-ore": 2},
+		},
+		{
+			name:      "entity placed from another item",
+			blueprint: Blueprint{Entities: []Entity{{Name: "curved-rail"}}},
+			items:     map[string]int{"rail": 4},
+			raw:       map[string]float64{"stone": 2, "iron-ore": 11},
+		},
+		{
+			name: "inserted items",
+			blueprint: Blueprint{Entities: []Entity{
+				{Name: "wooden-chest", Items: map[string]int{"wood": 3}},
+			}},
+			items: map[string]int{"wooden-chest": 1, "wood": 3},
+			raw:   map[string]float64{"wood": 5},
+		},
+		{
+			name: "tiles",
+			blueprint: Blueprint{Tiles: []Tile{
+				{Name: "stone-path"},
+				{Name: "hazard-concrete-left"},
+			}},
+			items: map[string]int{"stone-brick": 1, "hazard-concrete": 1},
+			raw:   map[string]float64{"stone": 3, "iron-ore": 0.1, "water": 10},
+		},
+		{
+			name:      "unknown entity",
+			blueprint: Blueprint{Entities: []Entity{{Name: "modded-machine"}}},
+			items:     map[string]int{"modded-machine": 1},
+			raw:       map[string]float64{"modded-machine": 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := test.blueprint.Materials()
+
+			if len(m.Items) != len(test.items) {
+				t.Errorf("Items: got %v, want %v", m.Items, test.items)
+			}
+
+			for item, count := range test.items {
+				if m.Items[item] != count {
+					t.Errorf("Items[%s]: got %d, want %d", item, m.Items[item], count)
+				}
+			}
+
+			if len(m.Raw) != len(test.raw) {
+				t.Errorf("Raw: got %v, want %v", m.Raw, test.raw)
+			}
+
+			for item, amount := range test.raw {
+				if math.Abs(m.Raw[item]-amount) > 1e-9 {
+					t.Errorf("Raw[%s]: got %v, want %v", item, m.Raw[item], amount)
+				}
+			}
+		})
+	}
+}
+
+func TestBookMaterials(t *testing.T) {
+	pipe := &Container{Blueprint: &Blueprint{Entities: []Entity{{Name: "pipe"}}}}
+	m := NewBook("Pipes", "", []*Container{pipe, pipe, pipe}).Materials()
+
+	if m.Entities["pipe"] != 3 || m.Items["pipe"] != 3 || m.Raw["iron-ore"] != 3 {
+		t.Errorf("got %+v, want 3 pipes made of 3 iron ore", m)
+	}
+}
+
+// Every recipe has to break down into raw resources without looping
+func TestRecipesTerminate(t *testing.T) {
+	for item, ingredients := range recipes {
+		seen := map[string]bool{item: true}
+
+		var walk func(ingredients Ingredients)
+		walk = func(ingredients Ingredients) {
+			for ingredient, amount := range ingredients {
+				if amount <= 0 {
+					t.Errorf("%s: non positive amount of %s", item, ingredient)
+				}
+
+				if seen[ingredient] {
+					t.Fatalf("%s: %s is its own ingredient", item, ingredient)
+				}
+
+				seen[ingredient] = true
+				walk(recipes[ingredient])
+				delete(seen, ingredient)
+			}
+		}
+
+		walk(ingredients)
+	}
+}
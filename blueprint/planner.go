@@ -0,0 +1,38 @@
+package blueprint
+
+type DeconstructionPlanner struct {
+	Item     string                 `json:"item"`
+	Label    string                 `json:"label,omitempty"`
+	Settings DeconstructionSettings `json:"settings"`
+	Version  Version                `json:"version"`
+}
+
+type DeconstructionSettings struct {
+	Description       string       `json:"description,omitempty"`
+	Icons             []Icon       `json:"icons,omitempty"`
+	EntityFilters     []ItemFilter `json:"entity_filters,omitempty"`
+	EntityFilterMode  int          `json:"entity_filter_mode,omitempty"`
+	TreesAndRocksOnly bool         `json:"trees_and_rocks_only,omitempty"`
+	TileFilters       []ItemFilter `json:"tile_filters,omitempty"`
+	TileFilterMode    int          `json:"tile_filter_mode,omitempty"`
+	TileSelectionMode int          `json:"tile_selection_mode,omitempty"`
+}
+
+type UpgradePlanner struct {
+	Item     string          `json:"item"`
+	Label    string          `json:"label,omitempty"`
+	Settings UpgradeSettings `json:"settings"`
+	Version  Version         `json:"version"`
+}
+
+type UpgradeSettings struct {
+	Description string          `json:"description,omitempty"`
+	Icons       []Icon          `json:"icons,omitempty"`
+	Mappers     []UpgradeMapper `json:"mappers,omitempty"`
+}
+
+type UpgradeMapper struct {
+	Index int       `json:"index"`
+	From  *SignalID `json:"from,omitempty"`
+	To    *SignalID `json:"to,omitempty"`
+}
@@ -0,0 +1,292 @@
+package blueprint
+
+import "math"
+
+// Size of an entity in tiles when facing north
+type Size struct {
+	Width  float64
+	Height float64
+}
+
+var entitySizes = map[string]Size{
+	"transport-belt":           {1, 1},
+	"fast-transport-belt":      {1, 1},
+	"express-transport-belt":   {1, 1},
+	"underground-belt":         {1, 1},
+	"fast-underground-belt":    {1, 1},
+	"express-underground-belt": {1, 1},
+	"splitter":                 {2, 1},
+	"fast-splitter":            {2, 1},
+	"express-splitter":         {2, 1},
+	"loader":                   {1, 2},
+	"fast-loader":              {1, 2},
+	"express-loader":           {1, 2},
+
+	"burner-inserter":       {1, 1},
+	"inserter":              {1, 1},
+	"long-handed-inserter":  {1, 1},
+	"fast-inserter":         {1, 1},
+	"filter-inserter":       {1, 1},
+	"stack-inserter":        {1, 1},
+	"stack-filter-inserter": {1, 1},
+
+	"wooden-chest":                    {1, 1},
+	"iron-chest":                      {1, 1},
+	"steel-chest":                     {1, 1},
+	"logistic-chest-active-provider":  {1, 1},
+	"logistic-chest-passive-provider": {1, 1},
+	"logistic-chest-storage":          {1, 1},
+	"logistic-chest-buffer":           {1, 1},
+	"logistic-chest-requester":        {1, 1},
+	"storage-tank":                    {3, 3},
+
+	"pipe":           {1, 1},
+	"pipe-to-ground": {1, 1},
+	"pump":           {1, 2},
+	"offshore-pump":  {1, 2},
+	"pumpjack":       {3, 3},
+
+	"small-electric-pole":  {1, 1},
+	"medium-electric-pole": {1, 1},
+	"big-electric-pole":    {2, 2},
+	"substation":           {2, 2},
+	"boiler":               {3, 2},
+	"steam-engine":         {3, 5},
+	"steam-turbine":        {3, 5},
+	"solar-panel":          {3, 3},
+	"accumulator":          {2, 2},
+	"nuclear-reactor":      {5, 5},
+	"heat-exchanger":       {3, 2},
+	"heat-pipe":            {1, 1},
+
+	"straight-rail":     {2, 2},
+	"curved-rail":       {4, 8},
+	"train-stop":        {2, 2},
+	"rail-signal":       {1, 1},
+	"rail-chain-signal": {1, 1},
+	"locomotive":        {2, 6},
+	"cargo-wagon":       {2, 6},
+	"fluid-wagon":       {2, 6},
+	"artillery-wagon":   {2, 6},
+
+	"roboport": {4, 4},
+
+	"small-lamp":            {1, 1},
+	"arithmetic-combinator": {1, 2},
+	"decider-combinator":    {1, 2},
+	"constant-combinator":   {1, 1},
+	"power-switch":          {2, 2},
+	"programmable-speaker":  {1, 1},
+
+	"burner-mining-drill":   {2, 2},
+	"electric-mining-drill": {3, 3},
+	"stone-furnace":         {2, 2},
+	"steel-furnace":         {2, 2},
+	"electric-furnace":      {3, 3},
+	"assembling-machine-1":  {3, 3},
+	"assembling-machine-2":  {3, 3},
+	"assembling-machine-3":  {3, 3},
+	"oil-refinery":          {5, 5},
+	"chemical-plant":        {3, 3},
+	"centrifuge":            {3, 3},
+	"lab":                   {3, 3},
+	"beacon":                {3, 3},
+	"rocket-silo":           {9, 9},
+
+	"stone-wall":          {1, 1},
+	"gate":                {1, 1},
+	"gun-turret":          {2, 2},
+	"laser-turret":        {2, 2},
+	"flamethrower-turret": {2, 3},
+	"artillery-turret":    {3, 3},
+	"radar":               {3, 3},
+	"land-mine":           {1, 1},
+}
+
+// Entities of the base game that can be placed in blueprints, independent of
+// the sizes above which may miss some
+var vanillaEntities = map[string]bool{
+	// Belts
+	"transport-belt":           true,
+	"fast-transport-belt":      true,
+	"express-transport-belt":   true,
+	"underground-belt":         true,
+	"fast-underground-belt":    true,
+	"express-underground-belt": true,
+	"splitter":                 true,
+	"fast-splitter":            true,
+	"express-splitter":         true,
+	"loader":                   true,
+	"fast-loader":              true,
+	"express-loader":           true,
+	"linked-belt":              true,
+
+	// Inserters
+	"burner-inserter":       true,
+	"inserter":              true,
+	"long-handed-inserter":  true,
+	"fast-inserter":         true,
+	"filter-inserter":       true,
+	"stack-inserter":        true,
+	"stack-filter-inserter": true,
+
+	// Storage
+	"wooden-chest":                    true,
+	"iron-chest":                      true,
+	"steel-chest":                     true,
+	"logistic-chest-active-provider":  true,
+	"logistic-chest-passive-provider": true,
+	"logistic-chest-storage":          true,
+	"logistic-chest-buffer":           true,
+	"logistic-chest-requester":        true,
+	"storage-tank":                    true,
+	"infinity-chest":                  true,
+	"linked-chest":                    true,
+
+	// Fluids
+	"pipe":           true,
+	"pipe-to-ground": true,
+	"pump":           true,
+	"offshore-pump":  true,
+	"pumpjack":       true,
+	"infinity-pipe":  true,
+
+	// Power
+	"small-electric-pole":       true,
+	"medium-electric-pole":      true,
+	"big-electric-pole":         true,
+	"substation":                true,
+	"boiler":                    true,
+	"steam-engine":              true,
+	"steam-turbine":             true,
+	"solar-panel":               true,
+	"accumulator":               true,
+	"nuclear-reactor":           true,
+	"heat-exchanger":            true,
+	"heat-pipe":                 true,
+	"heat-interface":            true,
+	"electric-energy-interface": true,
+
+	// Trains
+	"straight-rail":     true,
+	"curved-rail":       true,
+	"train-stop":        true,
+	"rail-signal":       true,
+	"rail-chain-signal": true,
+	"locomotive":        true,
+	"cargo-wagon":       true,
+	"fluid-wagon":       true,
+	"artillery-wagon":   true,
+
+	// Logistics network
+	"roboport": true,
+
+	// Circuit network
+	"small-lamp":            true,
+	"arithmetic-combinator": true,
+	"decider-combinator":    true,
+	"constant-combinator":   true,
+	"power-switch":          true,
+	"programmable-speaker":  true,
+
+	// Production
+	"burner-mining-drill":   true,
+	"electric-mining-drill": true,
+	"stone-furnace":         true,
+	"steel-furnace":         true,
+	"electric-furnace":      true,
+	"assembling-machine-1":  true,
+	"assembling-machine-2":  true,
+	"assembling-machine-3":  true,
+	"oil-refinery":          true,
+	"chemical-plant":        true,
+	"centrifuge":            true,
+	"lab":                   true,
+	"beacon":                true,
+	"rocket-silo":           true,
+
+	// Defense
+	"stone-wall":          true,
+	"gate":                true,
+	"gun-turret":          true,
+	"laser-turret":        true,
+	"flamethrower-turret": true,
+	"artillery-turret":    true,
+	"radar":               true,
+	"land-mine":           true,
+}
+
+// IsVanillaEntity reports whether the entity is part of the base game
+func IsVanillaEntity(name string) bool {
+	return vanillaEntities[name]
+}
+
+// EntitySize returns the footprint of the entity taking its direction into
+// account, unknown entities are assumed to be a single tile
+func EntitySize(entity Entity) Size {
+	size, ok := entitySizes[entity.Name]
+
+	if !ok {
+		size = Size{1, 1}
+	}
+
+	// East and west facing entities are rotated
+	if entity.Direction == 2 || entity.Direction == 6 {
+		size.Width, size.Height = size.Height, size.Width
+	}
+
+	return size
+}
+
+type Bounds struct {
+	MinX float64
+	MinY float64
+	MaxX float64
+	MaxY float64
+}
+
+func (b Bounds) Width() int {
+	return int(math.Ceil(b.MaxX - b.MinX))
+}
+
+func (b Bounds) Height() int {
+	return int(math.Ceil(b.MaxY - b.MinY))
+}
+
+// Bounds of all entities and tiles of the blueprint
+func (b *Blueprint) Bounds() Bounds {
+	bounds := Bounds{
+		MinX: math.Inf(1),
+		MinY: math.Inf(1),
+		MaxX: math.Inf(-1),
+		MaxY: math.Inf(-1),
+	}
+
+	extend := func(minX, minY, maxX, maxY float64) {
+		bounds.MinX = math.Min(bounds.MinX, minX)
+		bounds.MinY = math.Min(bounds.MinY, minY)
+		bounds.MaxX = math.Max(bounds.MaxX, maxX)
+		bounds.MaxY = math.Max(bounds.MaxY, maxY)
+	}
+
+	for _, entity := range b.Entities {
+		size := EntitySize(entity)
+		extend(
+			entity.Position.X-size.Width/2,
+			entity.Position.Y-size.Height/2,
+			entity.Position.X+size.Width/2,
+			entity.Position.Y+size.Height/2,
+		)
+	}
+
+	// Tile positions are their top left corner
+	for _, tile := range b.Tiles {
+		extend(tile.Position.X, tile.Position.Y, tile.Position.X+1, tile.Position.Y+1)
+	}
+
+	if math.IsInf(bounds.MinX, 1) {
+		return Bounds{}
+	}
+
+	return bounds
+}
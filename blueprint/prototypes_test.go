@@ -0,0 +1,106 @@
+package blueprint
+
+import "testing"
+
+func TestEntitySize(t *testing.T) {
+	tests := []struct {
+		entity Entity
+		want   Size
+	}{
+		{Entity{Name: "transport-belt"}, Size{1, 1}},
+		{Entity{Name: "assembling-machine-1", Direction: 2}, Size{3, 3}},
+		{Entity{Name: "splitter"}, Size{2, 1}},
+		{Entity{Name: "splitter", Direction: 2}, Size{1, 2}},
+		{Entity{Name: "splitter", Direction: 4}, Size{2, 1}},
+		{Entity{Name: "steam-engine", Direction: 6}, Size{5, 3}},
+		{Entity{Name: "modded-machine"}, Size{1, 1}},
+		{Entity{Name: "modded-machine", Direction: 2}, Size{1, 1}},
+	}
+
+	for _, test := range tests {
+		if got := EntitySize(test.entity); got != test.want {
+			t.Errorf("EntitySize(%s facing %d): got %v, want %v", test.entity.Name, test.entity.Direction, got, test.want)
+		}
+	}
+}
+
+func TestBounds(t *testing.T) {
+	tests := []struct {
+		name      string
+		blueprint Blueprint
+		want      Bounds
+		width     int
+		height    int
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "single entity",
+			blueprint: Blueprint{Entities: []Entity{
+				{Name: "assembling-machine-1", Position: Position{0.5, 0.5}},
+			}},
+			want:   Bounds{-1, -1, 2, 2},
+			width:  3,
+			height: 3,
+		},
+		{
+			name: "rotated entity",
+			blueprint: Blueprint{Entities: []Entity{
+				{Name: "splitter", Position: Position{0.5, 1}, Direction: 2},
+			}},
+			want:   Bounds{0, 0, 1, 2},
+			width:  1,
+			height: 2,
+		},
+		{
+			name: "entities and tiles",
+			blueprint: Blueprint{
+				Entities: []Entity{{Name: "transport-belt", Position: Position{0.5, 0.5}}},
+				Tiles:    []Tile{{Name: "stone-path", Position: Position{3, 4}}},
+			},
+			want:   Bounds{0, 0, 4, 5},
+			width:  4,
+			height: 5,
+		},
+		{
+			name: "tiles only",
+			blueprint: Blueprint{Tiles: []Tile{
+				{Name: "concrete", Position: Position{-2, -2}},
+				{Name: "concrete", Position: Position{-1, -2}},
+			}},
+			want:   Bounds{-2, -2, 0, -1},
+			width:  2,
+			height: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bounds := test.blueprint.Bounds()
+
+			if bounds != test.want {
+				t.Errorf("Bounds: got %v, want %v", bounds, test.want)
+			}
+
+			if bounds.Width() != test.width || bounds.Height() != test.height {
+				t.Errorf("size: got %dx%d, want %dx%d", bounds.Width(), bounds.Height(), test.width, test.height)
+			}
+		})
+	}
+}
+
+func TestIsVanillaEntity(t *testing.T) {
+	// Entities with a known size are all part of the base game
+	for name := range entitySizes {
+		if !IsVanillaEntity(name) {
+			t.Errorf("IsVanillaEntity(%s): got false", name)
+		}
+	}
+
+	for _, name := range []string{"", "modded-machine", "Transport-belt", "iron-plate"} {
+		if IsVanillaEntity(name) {
+			t.Errorf("IsVanillaEntity(%q): got true", name)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package blueprint
+
+// Ingredients needed to craft a single unit of an item
+type Ingredients map[string]float64
+
+// Vanilla recipes normalized to one unit of output. Items missing from this
+// table (ores, fluids, modded items) are treated as raw resources.
+var recipes = map[string]Ingredients{
+	// Intermediates
+	"iron-plate":           {"iron-ore": 1},
+	"copper-plate":         {"copper-ore": 1},
+	"steel-plate":          {"iron-plate": 5},
+	"stone-brick":          {"stone": 2},
+	"iron-gear-wheel":      {"iron-plate": 2},
+	"iron-stick":           {"iron-plate": 0.5},
+	"copper-cable":         {"copper-plate": 0.5},
+	"pipe":                 {"iron-plate": 1},
+	"electronic-circuit":   {"iron-plate": 1, "copper-cable": 3},
+	"advanced-circuit":     {"electronic-circuit": 2, "plastic-bar": 2, "copper-cable": 4},
+	"processing-unit":      {"electronic-circuit": 20, "advanced-circuit": 2, "sulfuric-acid": 5},
+	"plastic-bar":          {"petroleum-gas": 10, "coal": 0.5},
+	"sulfur":               {"petroleum-gas": 15, "water": 15},
+	"sulfuric-acid":        {"sulfur": 0.1, "iron-plate": 0.02, "water": 2},
+	"battery":              {"iron-plate": 1, "copper-plate": 1, "sulfuric-acid": 20},
+	"engine-unit":          {"steel-plate": 1, "iron-gear-wheel": 1, "pipe": 2},
+	"electric-engine-unit": {"engine-unit": 1, "electronic-circuit": 2, "lubricant": 15},
+	"lubricant":            {"heavy-oil": 1},
+	"explosives":           {"sulfur": 0.5, "coal": 0.5, "water": 5},
+
+	// Tiles
+	"concrete":                {"stone-brick": 0.5, "iron-ore": 0.1, "water": 10},
+	"hazard-concrete":         {"concrete": 1},
+	"refined-concrete":        {"concrete": 2, "iron-stick": 0.8, "steel-plate": 0.1, "water": 10},
+	"refined-hazard-concrete": {"refined-concrete": 1},
+	"landfill":                {"stone": 20},
+
+	// Modules
+	"speed-module":          {"advanced-circuit": 5, "electronic-circuit": 5},
+	"speed-module-2":        {"speed-module": 4, "advanced-circuit": 5, "processing-unit": 5},
+	"speed-module-3":        {"speed-module-2": 5, "advanced-circuit": 5, "processing-unit": 5},
+	"productivity-module":   {"advanced-circuit": 5, "electronic-circuit": 5},
+	"productivity-module-2": {"productivity-module": 4, "advanced-circuit": 5, "processing-unit": 5},
+	"productivity-module-3": {"productivity-module-2": 5, "advanced-circuit": 5, "processing-unit": 5},
+	"effectivity-module":    {"advanced-circuit": 5, "electronic-circuit": 5},
+	"effectivity-module-2":  {"effectivity-module": 4, "advanced-circuit": 5, "processing-unit": 5},
+	"effectivity-module-3":  {"effectivity-module-2": 5, "advanced-circuit": 5, "processing-unit": 5},
+
+	// Storage
+	"wooden-chest":                    {"wood": 2},
+	"iron-chest":                      {"iron-plate": 8},
+	"steel-chest":                     {"steel-plate": 8},
+	"storage-tank":                    {"iron-plate": 20, "steel-plate": 5},
+	"logistic-chest-active-provider":  {"steel-chest": 1, "electronic-circuit": 3, "advanced-circuit": 1},
+	"logistic-chest-passive-provider": {"steel-chest": 1, "electronic-circuit": 3, "advanced-circuit": 1},
+	"logistic-chest-storage":          {"steel-chest": 1, "electronic-circuit": 3, "advanced-circuit": 1},
+	"logistic-chest-buffer":           {"steel-chest": 1, "electronic-circuit": 3, "advanced-circuit": 1},
+	"logistic-chest-requester":        {"steel-chest": 1, "electronic-circuit": 3, "advanced-circuit": 1},
+
+	// Belts
+	"transport-belt":           {"iron-plate": 0.5, "iron-gear-wheel": 0.5},
+	"fast-transport-belt":      {"iron-gear-wheel": 5, "transport-belt": 1},
+	"express-transport-belt":   {"iron-gear-wheel": 10, "fast-transport-belt": 1, "lubricant": 20},
+	"underground-belt":         {"iron-plate": 5, "transport-belt": 2.5},
+	"fast-underground-belt":    {"iron-gear-wheel": 20, "underground-belt": 1},
+	"express-underground-belt": {"iron-gear-wheel": 40, "fast-underground-belt": 1, "lubricant": 20},
+	"splitter":                 {"electronic-circuit": 5, "iron-plate": 5, "transport-belt": 4},
+	"fast-splitter":            {"splitter": 1, "iron-gear-wheel": 10, "electronic-circuit": 10},
+	"express-splitter":         {"fast-splitter": 1, "iron-gear-wheel": 10, "advanced-circuit": 10, "lubricant": 80},
+
+	// Inserters
+	"burner-inserter":       {"iron-plate": 1, "iron-gear-wheel": 1},
+	"inserter":              {"electronic-circuit": 1, "iron-gear-wheel": 1, "iron-plate": 1},
+	"long-handed-inserter":  {"inserter": 1, "iron-gear-wheel": 1, "iron-plate": 1},
+	"fast-inserter":         {"electronic-circuit": 2, "iron-plate": 2, "inserter": 1},
+	"filter-inserter":       {"fast-inserter": 1, "electronic-circuit": 4},
+	"stack-inserter":        {"iron-gear-wheel": 15, "electronic-circuit": 15, "advanced-circuit": 1, "fast-inserter": 1},
+	"stack-filter-inserter": {"stack-inserter": 1, "electronic-circuit": 5},
+
+	// Power
+	"small-electric-pole":  {"wood": 0.5, "copper-cable": 1},
+	"medium-electric-pole": {"steel-plate": 2, "copper-plate": 2, "iron-stick": 4},
+	"big-electric-pole":    {"steel-plate": 5, "copper-plate": 5, "iron-stick": 8},
+	"substation":           {"steel-plate": 10, "advanced-circuit": 5, "copper-plate": 5},
+	"boiler":               {"stone-furnace": 1, "pipe": 4},
+	"steam-engine":         {"iron-gear-wheel": 8, "pipe": 5, "iron-plate": 10},
+	"steam-turbine":        {"iron-gear-wheel": 50, "copper-plate": 50, "pipe": 20},
+	"solar-panel":          {"steel-plate": 5, "electronic-circuit": 15, "copper-plate": 5},
+	"accumulator":          {"iron-plate": 2, "battery": 5},
+	"nuclear-reactor":      {"concrete": 500, "steel-plate": 500, "advanced-circuit": 500, "copper-plate": 500},
+	"heat-exchanger":       {"steel-plate": 10, "copper-plate": 100, "pipe": 10},
+	"heat-pipe":            {"steel-plate": 10, "copper-plate": 20},
+
+	// Fluids
+	"pipe-to-ground": {"pipe": 5, "iron-plate": 2.5},
+	"pump":           {"engine-unit": 1, "steel-plate": 1, "pipe": 1},
+	"offshore-pump":  {"electronic-circuit": 2, "pipe": 1, "iron-gear-wheel": 1},
+	"pumpjack":       {"steel-plate": 5, "iron-gear-wheel": 10, "electronic-circuit": 5, "pipe": 10},
+
+	// Trains
+	"rail":              {"stone": 0.5, "iron-stick": 0.5, "steel-plate": 0.5},
+	"train-stop":        {"electronic-circuit": 5, "iron-plate": 10, "steel-plate": 3},
+	"rail-signal":       {"electronic-circuit": 5, "iron-plate": 5},
+	"rail-chain-signal": {"electronic-circuit": 5, "iron-plate": 5},
+	"locomotive":        {"engine-unit": 20, "electronic-circuit": 10, "steel-plate": 30},
+	"cargo-wagon":       {"iron-gear-wheel": 10, "iron-plate": 20, "steel-plate": 20},
+	"fluid-wagon":       {"iron-gear-wheel": 10, "steel-plate": 16, "pipe": 8, "storage-tank": 1},
+	"artillery-wagon":   {"engine-unit": 64, "iron-gear-wheel": 10, "steel-plate": 40, "pipe": 16, "advanced-circuit": 20},
+
+	// Logistics network
+	"roboport": {"steel-plate": 45, "iron-gear-wheel": 45, "advanced-circuit": 45},
+
+	// Circuit network
+	"small-lamp":            {"electronic-circuit": 1, "copper-cable": 3, "iron-plate": 1},
+	"red-wire":              {"electronic-circuit": 1, "copper-cable": 1},
+	"green-wire":            {"electronic-circuit": 1, "copper-cable": 1},
+	"arithmetic-combinator": {"copper-cable": 5, "electronic-circuit": 5},
+	"decider-combinator":    {"copper-cable": 5, "electronic-circuit": 5},
+	"constant-combinator":   {"copper-cable": 5, "electronic-circuit": 2},
+	"power-switch":          {"iron-plate": 5, "copper-cable": 5, "electronic-circuit": 2},
+	"programmable-speaker":  {"iron-plate": 3, "iron-stick": 4, "copper-cable": 5, "electronic-circuit": 4},
+
+	// Production
+	"burner-mining-drill":   {"iron-gear-wheel": 3, "stone-furnace": 1, "iron-plate": 3},
+	"electric-mining-drill": {"electronic-circuit": 3, "iron-gear-wheel": 5, "iron-plate": 10},
+	"stone-furnace":         {"stone": 5},
+	"steel-furnace":         {"steel-plate": 6, "stone-brick": 10},
+	"electric-furnace":      {"steel-plate": 10, "advanced-circuit": 5, "stone-brick": 10},
+	"assembling-machine-1":  {"electronic-circuit": 3, "iron-gear-wheel": 5, "iron-plate": 9},
+	"assembling-machine-2":  {"steel-plate": 2, "electronic-circuit": 3, "iron-gear-wheel": 5, "assembling-machine-1": 1},
+	"assembling-machine-3":  {"speed-module": 4, "assembling-machine-2": 2},
+	"oil-refinery":          {"steel-plate": 15, "iron-gear-wheel": 10, "stone-brick": 10, "electronic-circuit": 10, "pipe": 10},
+	"chemical-plant":        {"steel-plate": 5, "iron-gear-wheel": 5, "electronic-circuit": 5, "pipe": 5},
+	"centrifuge":            {"concrete": 100, "steel-plate": 50, "advanced-circuit": 100, "iron-gear-wheel": 100},
+	"lab":                   {"electronic-circuit": 10, "iron-gear-wheel": 10, "transport-belt": 4},
+	"beacon":                {"electronic-circuit": 20, "advanced-circuit": 20, "steel-plate": 10, "copper-cable": 10},
+	"rocket-silo":           {"concrete": 1000, "electric-engine-unit": 200, "steel-plate": 1000, "processing-unit": 200, "pipe": 100},
+
+	// Defense
+	"stone-wall":          {"stone-brick": 5},
+	"gate":                {"stone-wall": 1, "steel-plate": 2, "electronic-circuit": 2},
+	"gun-turret":          {"iron-gear-wheel": 10, "copper-plate": 10, "iron-plate": 20},
+	"laser-turret":        {"steel-plate": 20, "electronic-circuit": 20, "battery": 12},
+	"flamethrower-turret": {"steel-plate": 30, "iron-gear-wheel": 15, "pipe": 10, "engine-unit": 5},
+	"artillery-turret":    {"steel-plate": 60, "concrete": 60, "iron-gear-wheel": 40, "advanced-circuit": 20},
+	"radar":               {"electronic-circuit": 5, "iron-gear-wheel": 5, "iron-plate": 10},
+	"land-mine":           {"steel-plate": 0.25, "explosives": 0.5},
+}
+
+// Entities placed from a different item or from several of them
+var entityItems = map[string]map[string]int{
+	"straight-rail": {"rail": 1},
+	"curved-rail":   {"rail": 4},
+}
+
+// Tiles placed from a differently named item
+var tileItems = map[string]string{
+	"stone-path":                    "stone-brick",
+	"hazard-concrete-left":          "hazard-concrete",
+	"hazard-concrete-right":         "hazard-concrete",
+	"refined-hazard-concrete-left":  "refined-hazard-concrete",
+	"refined-hazard-concrete-right": "refined-hazard-concrete",
+}
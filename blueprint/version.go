@@ -0,0 +1,31 @@
+package blueprint
+
+import "fmt"
+
+// Version is the game version packed into four 16 bit parts:
+// major, minor, patch and developer
+type Version uint64
+
+func NewVersion(major, minor, patch, developer uint16) Version {
+	return Version(uint64(major)<<48 | uint64(minor)<<32 | uint64(patch)<<16 | uint64(developer))
+}
+
+func (v Version) Major() uint16 {
+	return uint16(v >> 48)
+}
+
+func (v Version) Minor() uint16 {
+	return uint16(v >> 32)
+}
+
+func (v Version) Patch() uint16 {
+	return uint16(v >> 16)
+}
+
+func (v Version) Developer() uint16 {
+	return uint16(v)
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), v.Patch())
+}
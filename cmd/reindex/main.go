@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/BlooperDB/API"
+	"github.com/BlooperDB/API/db"
+	"github.com/BlooperDB/API/storage"
+)
+
+func main() {
+	var postgresHost string
+	var minioHost string
+	var storageBackend string
+	var storagePath string
+	var scoreDecay float64
+	var scores bool
+
+	flag.StringVar(&postgresHost, "postgres-host", "postgres", "sets the postgres host to connect to")
+	flag.StringVar(&minioHost, "minio-host", "minio", "sets the minio host to connect to")
+	flag.StringVar(&storageBackend, "storage", "minio", "sets the storage backend (minio, local or memory)")
+	flag.StringVar(&storagePath, "storage-path", "data", "sets the directory of the local storage backend")
+	flag.Float64Var(&scoreDecay, "score-decay", 1, "sets the weight of ratings per newer revision of a blueprint, must match the API")
+	flag.BoolVar(&scores, "scores", false, "recomputes the score of every blueprint, needed after changing score-decay")
+	flag.Parse()
+
+	if scoreDecay <= 0 || scoreDecay > 1 {
+		log.Fatal("score-decay must be greater than 0 and at most 1")
+	}
+
+	db.ScoreDecay = scoreDecay
+
+	blooper.InitializeDB(postgresHost)
+
+	if scores {
+		fmt.Println("Recomputing blueprint scores")
+		db.RefreshBlueprintScores()
+	}
+
+	if err := blooper.InitializeStorage(storageBackend, minioHost, storagePath, os.Getenv("STORAGE_BASE_URL")); err != nil {
+		log.Fatal(err)
+	}
+
+	revisions := db.FindUnindexedRevisions()
+
+	for _, rev := range revisions {
+		container := storage.GetRevisionBlueprint(rev)
+		if container == nil {
+			fmt.Printf("Skipping %d: unable to decode blueprint string\n", rev.ID)
+			continue
+		}
+
+		fmt.Printf("Indexing %d\n", rev.ID)
+
+		rev.Kind = container.Kind()
+		rev.Save()
+
+		if err := db.IndexRevision(rev, container); err != nil {
+			fmt.Printf("Failed %d: %v\n", rev.ID, err)
+		}
+	}
+}
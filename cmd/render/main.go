@@ -1,34 +1,178 @@
 package main
 
 import (
-	"fmt"
-
 	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/BlooperDB/API"
 	"github.com/BlooperDB/API/db"
-	"github.com/BlooperDB/API/storage"
+	"github.com/BlooperDB/API/render"
 )
 
 func main() {
 	var postgresHost string
 	var minioHost string
+	var storageBackend string
+	var storagePath string
+	var concurrency int
+	var interval time.Duration
+	var progressInterval time.Duration
+	var ids string
+	var all bool
+	var dryRun bool
+	var once bool
+	var variants string
 
 	flag.StringVar(&postgresHost, "postgres-host", "postgres", "sets the postgres host to connect to")
 	flag.StringVar(&minioHost, "minio-host", "minio", "sets the minio host to connect to")
+	flag.StringVar(&storageBackend, "storage", "minio", "sets the storage backend (minio, local or memory)")
+	flag.StringVar(&storagePath, "storage-path", "data", "sets the directory of the local storage backend")
+	flag.IntVar(&concurrency, "concurrency", 2, "sets the number of revisions rendered at once")
+	flag.DurationVar(&interval, "interval", render.PollInterval, "sets how often idle workers look for new jobs")
+	flag.DurationVar(&progressInterval, "progress", 30*time.Second, "sets how often progress is printed")
+	flag.StringVar(&ids, "ids", "", "queues and only renders the comma separated revision ids")
+	flag.BoolVar(&all, "all", false, "queues every revision for rendering again")
+	flag.BoolVar(&dryRun, "dry-run", false, "prints the revisions that would be rendered without rendering them")
+	flag.BoolVar(&once, "once", false, "exits once no job is due instead of waiting for new ones")
+	flag.StringVar(&variants, "render-variants", "", "sets the JSON file listing the render variants")
 	flag.Parse()
 
+	revisionIds, err := parseIds(ids)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if variants != "" {
+		if err := render.LoadVariants(variants); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	blooper.InitializeDB(postgresHost)
 
-	blooper.InitializeStorage(minioHost)
+	if err := blooper.InitializeStorage(storageBackend, minioHost, storagePath, os.Getenv("STORAGE_BASE_URL")); err != nil {
+		log.Fatal(err)
+	}
+
+	if dryRun {
+		printDryRun(revisionIds, all)
+		return
+	}
 
-	revisions := db.FindUnrenderedRevisions()
+	if all {
+		db.QueueAllRenderJobs()
+	}
 
-	for _, rev := range revisions {
-		revision := storage.GetRevision(rev.ID)
-		if revision != nil {
-			fmt.Println("Rendering " + string(rev.ID))
-			storage.RenderAndSaveAndUpdateBlueprint(*revision, rev)
+	for _, id := range revisionIds {
+		render.Queue(id)
+	}
+
+	options := render.Options{
+		Workers:  concurrency,
+		Interval: interval,
+		Once:     once,
+	}
+
+	// Only the given revisions are rendered unless every revision was queued
+	if !all {
+		options.Revisions = revisionIds
+	}
+
+	pool := render.Start(options)
+
+	fmt.Printf("[Render] Started %d workers\n", concurrency)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		pool.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			printProgress(pool)
+		case sig := <-signals:
+			fmt.Printf("[Render] Received %s, waiting for running jobs\n", sig)
+			pool.Stop()
+			printProgress(pool)
+			return
+		case <-done:
+			printProgress(pool)
+			return
 		}
 	}
 }
+
+func parseIds(ids string) ([]uint, error) {
+	var result []uint
+
+	for _, id := range strings.Split(ids, ",") {
+		id = strings.TrimSpace(id)
+
+		if id == "" {
+			continue
+		}
+
+		n, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid revision id %q", id)
+		}
+
+		result = append(result, uint(n))
+	}
+
+	return result, nil
+}
+
+func printDryRun(revisionIds []uint, all bool) {
+	if all {
+		fmt.Println("Would queue every revision")
+	}
+
+	for _, id := range revisionIds {
+		fmt.Printf("Would queue revision %d\n", id)
+	}
+
+	// Given revisions are queued right away and are all that is rendered
+	if len(revisionIds) > 0 && !all {
+		for _, id := range revisionIds {
+			fmt.Printf("Would render revision %d\n", id)
+		}
+
+		fmt.Printf("%d jobs due\n", len(revisionIds))
+		return
+	}
+
+	jobs := db.FindDueRenderJobs()
+
+	for _, job := range jobs {
+		fmt.Printf("Would render revision %d (attempt %d)\n", job.RevisionID, job.Attempts+1)
+	}
+
+	fmt.Printf("%d jobs due\n", len(jobs))
+}
+
+func printProgress(pool *render.Pool) {
+	progress := pool.Progress()
+
+	fmt.Printf("[Render] %d rendered, %d retrying, %d failed, %d queued\n",
+		progress.Rendered,
+		progress.Retrying,
+		progress.Failed,
+		db.CountRenderJobs(db.RenderJobQueued),
+	)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseIds(t *testing.T) {
+	tests := []struct {
+		ids     string
+		want    []uint
+		invalid bool
+	}{
+		{ids: ""},
+		{ids: " , "},
+		{ids: "1", want: []uint{1}},
+		{ids: "3,1,2", want: []uint{3, 1, 2}},
+		{ids: " 4 ,, 5 ", want: []uint{4, 5}},
+		{ids: "4294967295", want: []uint{4294967295}},
+		{ids: "4294967296", invalid: true},
+		{ids: "1,two", invalid: true},
+		{ids: "-1", invalid: true},
+		{ids: "1.5", invalid: true},
+	}
+
+	for _, test := range tests {
+		ids, err := parseIds(test.ids)
+
+		if test.invalid {
+			if err == nil {
+				t.Errorf("parseIds(%q): got %v, want an error", test.ids, ids)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("parseIds(%q): %v", test.ids, err)
+		} else if !reflect.DeepEqual(ids, test.want) {
+			t.Errorf("parseIds(%q): got %v, want %v", test.ids, ids, test.want)
+		}
+	}
+}
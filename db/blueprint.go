@@ -2,6 +2,7 @@ package db
 
 import (
 	"strings"
+	"time"
 
 	"github.com/jinzhu/gorm"
 )
@@ -13,58 +14,10 @@ type Blueprint struct {
 	Name         string `gorm:"not null"`
 	Description  string `gorm:"not null"`
 	LastRevision uint   `gorm:"not null"`
-}
-
-func SearchBlueprints(query string, offset int, limit int) []*Blueprint {
-	query = strings.ToLower(query)
-	split := strings.Split(query, " ")
-	joined := "(" + strings.Join(split, "|") + ")%"
-	fullJoined := "%" + joined
-
-	var blueprints []*Blueprint
-	db.Raw(`
-		SELECT *
-		FROM blueprints b
-		WHERE id IN (
-			SELECT blueprint_id
-			FROM blueprint_tags
-			WHERE tag_id IN (
-				SELECT id
-				FROM tags
-				WHERE LOWER("name") SIMILAR TO ?
-			)
-		)
-		OR id IN (
-			SELECT blueprint_id
-			FROM revisions
-			WHERE LOWER("changes") SIMILAR TO ?
-		)
-		OR LOWER("name") SIMILAR TO ?
-		OR LOWER("description") SIMILAR TO ?
-		ORDER BY (
-			select (
-				SUM(case when thumbs_up = true then 1 else 0 end) 
-				- 
-				SUM(case when thumbs_up = false then 1 else 0 end)
-			)
-			from ratings
-			where revision_id = (
-				select id
-				from revisions
-				where blueprint_id = b.id
-				limit 1
-			)
-		), id DESC
-		OFFSET ?
-		LIMIT ?
-	`, joined, fullJoined, fullJoined, fullJoined, offset, limit).Scan(&blueprints)
-	return blueprints
-}
 
-func GetAllBlueprints(offset int, limit int) []*Blueprint {
-	var blueprints []*Blueprint
-	db.Offset(offset).Limit(limit).Find(&blueprints)
-	return blueprints
+	// Set on blueprints forked from another one
+	ForkedFromBlueprintID *uint `gorm:"index"`
+	ForkedFromRevisionID  *uint
 }
 
 func GetBlueprintById(id uint) *Blueprint {
@@ -188,6 +141,60 @@ func (m *Blueprint) GetLatestRevision() *Revision {
 	return FindLatestRevisionFromBlueprint(m.ID)
 }
 
+func (m Blueprint) GetForkedFrom() *Blueprint {
+	if m.ForkedFromBlueprintID == nil {
+		return nil
+	}
+	return GetBlueprintById(*m.ForkedFromBlueprintID)
+}
+
+func (m Blueprint) GetForkedFromRevision() *Revision {
+	if m.ForkedFromRevisionID == nil {
+		return nil
+	}
+	return GetRevisionById(*m.ForkedFromRevisionID)
+}
+
+func (m Blueprint) GetForks() []*Blueprint {
+	var forks []*Blueprint
+	db.Where("forked_from_blueprint_id = ?", m.ID).Order("created_at desc").Find(&forks)
+	return forks
+}
+
+func (m Blueprint) CountForks() int {
+	var count int
+	db.Model(&Blueprint{}).Where("forked_from_blueprint_id = ?", m.ID).Count(&count)
+	return count
+}
+
+type blueprintForks struct {
+	ForkedFromBlueprintID uint
+	Count                 int
+}
+
+// CountBlueprintForks counts the forks of each blueprint at once, blueprints
+// without forks are missing
+func CountBlueprintForks(ids []uint) map[uint]int {
+	counts := make(map[uint]int)
+
+	if len(ids) == 0 {
+		return counts
+	}
+
+	var forks []blueprintForks
+	db.Model(&Blueprint{}).
+		Select("forked_from_blueprint_id, count(*) AS count").
+		Where("forked_from_blueprint_id IN (?)", ids).
+		Group("forked_from_blueprint_id").
+		Scan(&forks)
+
+	for _, f := range forks {
+		counts[f.ForkedFromBlueprintID] = f.Count
+	}
+
+	return counts
+}
+
 func (m *Blueprint) GetThumbnail() string {
 	var checksum []string
 
@@ -201,200 +208,280 @@ func (m *Blueprint) GetThumbnail() string {
 	return checksum[0]
 }
 
-func PopularBlueprints(offset int, limit int) []*Blueprint {
-	var blueprints []*Blueprint
-	db.Raw(`
-		SELECT *
-		FROM blueprints b
-		ORDER BY
-			(
-				SELECT inside.hotness
-				FROM (
-					SELECT
-					round(
-						CAST(
-							(
-								(CASE WHEN scoring.score > 0 THEN 1 WHEN 100 = 0 THEN 0 ELSE -1 END)
-								*
-								log(10, greatest(abs(scoring.score), 1))
-								+
-								((EXTRACT(epoch FROM b.created_at) - 1400000000) / 45000)
-							) AS numeric
-						),
-						7
-					) AS hotness
-					FROM (
-						SELECT
-						(
-							SELECT (
-								SUM(CASE WHEN thumbs_up = true THEN 1 ELSE 0 END)
-								-
-								SUM(CASE WHEN thumbs_up = false THEN 1 ELSE 0 END)
-							)
-							FROM ratings
-							WHERE revision_id = (
-								SELECT id
-								FROM revisions
-								WHERE blueprint_id = b.id
-								LIMIT 1
-							)
-						) AS score
-					) AS scoring
-				) AS inside
-			) DESC,
-			id DESC
-		OFFSET ?
-		LIMIT ?
-	`, offset, limit).Scan(&blueprints)
-	return blueprints
+// BlueprintFilter restricts listings by the indexed metadata of the latest
+// revision of each blueprint
+type BlueprintFilter struct {
+	HasEntities     []string
+	WithoutEntities []string
+	MaxWidth        int
+	MaxHeight       int
+	GameVersion     string
+	Tags            []string
+	Author          uint
+	Kinds           []string
+	ForkedFrom      uint
 }
 
-func TopBlueprints(offset int, limit int) []*Blueprint {
-	var blueprints []*Blueprint
-	db.Raw(`
-		SELECT *
-		FROM blueprints b
-		ORDER BY (
-			select (
-				SUM(case when thumbs_up = true then 1 else 0 end)
-				-
-				SUM(case when thumbs_up = false then 1 else 0 end)
-			)
-			from ratings
-			where revision_id = (
-				select id
-				from revisions
-				where blueprint_id = b.id
-				limit 1
-			)
-		), id DESC
-		OFFSET ?
-		LIMIT ?
-	`, offset, limit).Scan(&blueprints)
-	return blueprints
+const latestRevisionQuery = `(
+	SELECT id
+	FROM revisions
+	WHERE blueprint_id = b.id AND deleted_at IS NULL
+	ORDER BY revision DESC
+	LIMIT 1
+)`
+
+func (f BlueprintFilter) where() (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+
+	for _, entity := range f.HasEntities {
+		clauses = append(clauses, `EXISTS (
+			SELECT 1
+			FROM revision_entities
+			WHERE revision_id = `+latestRevisionQuery+` AND name = ?
+		)`)
+		args = append(args, entity)
+	}
+
+	for _, entity := range f.WithoutEntities {
+		clauses = append(clauses, `NOT EXISTS (
+			SELECT 1
+			FROM revision_entities
+			WHERE revision_id = `+latestRevisionQuery+` AND name = ?
+		)`)
+		args = append(args, entity)
+	}
+
+	for _, tag := range f.Tags {
+		clauses = append(clauses, `EXISTS (
+			SELECT 1
+			FROM blueprint_tags bt
+			JOIN tags t ON (t.id = bt.tag_id)
+			WHERE bt.blueprint_id = b.id AND bt.deleted_at IS NULL AND t.name = ?
+		)`)
+		args = append(args, tag)
+	}
+
+	if f.Author != 0 {
+		clauses = append(clauses, "b.user_id = ?")
+		args = append(args, f.Author)
+	}
+
+	if f.ForkedFrom != 0 {
+		clauses = append(clauses, "b.forked_from_blueprint_id = ?")
+		args = append(args, f.ForkedFrom)
+	}
+
+	if len(f.Kinds) > 0 {
+		clauses = append(clauses, `EXISTS (
+			SELECT 1
+			FROM revisions
+			WHERE id = `+latestRevisionQuery+` AND kind IN (?)
+		)`)
+		args = append(args, f.Kinds)
+	}
+
+	var metadata []string
+
+	if f.MaxWidth > 0 {
+		metadata = append(metadata, "width <= ?")
+		args = append(args, f.MaxWidth)
+	}
+
+	if f.MaxHeight > 0 {
+		metadata = append(metadata, "height <= ?")
+		args = append(args, f.MaxHeight)
+	}
+
+	if f.GameVersion != "" {
+		// Matches both exact versions and prefixes such as "0.16"
+		metadata = append(metadata, "(game_version = ? OR game_version LIKE ?)")
+		args = append(args, f.GameVersion, f.GameVersion+".%")
+	}
+
+	if len(metadata) > 0 {
+		clauses = append(clauses, `EXISTS (
+			SELECT 1
+			FROM revision_metadata
+			WHERE revision_id = `+latestRevisionQuery+` AND `+strings.Join(metadata, " AND ")+`
+		)`)
+	}
+
+	return strings.Join(clauses, " AND "), args
 }
 
-func NewBlueprints(offset int, limit int) []*Blueprint {
-	var blueprints []*Blueprint
-	db.Raw(`
-		SELECT *
-		FROM blueprints b
-		ORDER BY created_at DESC, id DESC
-		OFFSET ?
-		LIMIT ?
-	`, offset, limit).Scan(&blueprints)
-	return blueprints
+// Sort key expressions of the listing orders, evaluated with the blueprint
+// as "b". Keys are cast back to keyType when compared against a cursor.
+// Keys depending on the current time take the reference time as their first
+// references parameters.
+type blueprintOrder struct {
+	key        string
+	keyType    string
+	references int
 }
 
-func FindBlueprintsDynamic(query string, offset int, limit int, order string, ascending bool) []*Blueprint {
-	query = strings.ToLower(query)
-	split := strings.Split(query, " ")
-	joined := "(" + strings.Join(split, "|") + ")%"
-	fullJoined := "%" + joined
-
-	ascdesc := "DESC"
-
-	if ascending {
-		ascdesc = "ASC"
-	}
-
-	ordering := "ORDER BY created_at " + ascdesc + ", id " + ascdesc
-
-	switch order {
-	case "TOP":
-		ordering = `
-		ORDER BY (
-			select (
-				SUM(case when thumbs_up = true then 1 else 0 end)
-				-
-				SUM(case when thumbs_up = false then 1 else 0 end)
-			)
-			from ratings
-			where revision_id = (
-				select id
-				from revisions
-				where blueprint_id = b.id
-				limit 1
-			)
-		), id
-		` + ascdesc
-	case "POPULAR":
-		ordering = `
-		ORDER BY
-			(
-				SELECT inside.hotness
-				FROM (
-					SELECT
-					round(
-						CAST(
-							(
-								(CASE WHEN scoring.score > 0 THEN 1 WHEN 100 = 0 THEN 0 ELSE -1 END)
-								*
-								log(10, greatest(abs(scoring.score), 1))
-								+
-								((EXTRACT(epoch FROM b.created_at) - 1400000000) / 45000)
-							) AS numeric
-						),
-						7
-					) AS hotness
-					FROM (
-						SELECT
-						(
-							SELECT (
-								SUM(CASE WHEN thumbs_up = true THEN 1 ELSE 0 END)
-								-
-								SUM(CASE WHEN thumbs_up = false THEN 1 ELSE 0 END)
-							)
-							FROM ratings
-							WHERE revision_id = (
-								SELECT id
-								FROM revisions
-								WHERE blueprint_id = b.id
-								LIMIT 1
-							)
-						) AS score
-					) AS scoring
-				) AS inside
-			) ` + ascdesc + `,
-			id
-		` + ascdesc
-	}
-
-	var blueprints []*Blueprint
-
-	if query != "" {
-		db.Raw(`
-			SELECT *
-			FROM blueprints b
-			WHERE id IN (
-				SELECT blueprint_id
-				FROM blueprint_tags
-				WHERE tag_id IN (
-					SELECT id
-					FROM tags
-					WHERE LOWER("name") SIMILAR TO ?
-				)
-			)
-			OR id IN (
-				SELECT blueprint_id
-				FROM revisions
-				WHERE LOWER("changes") SIMILAR TO ?
-			)
-			OR LOWER("name") SIMILAR TO ?
-			OR LOWER("description") SIMILAR TO ?
-			`+ordering+`
-			OFFSET ?
-			LIMIT ?
-		`, joined, fullJoined, fullJoined, fullJoined, offset, limit).Scan(&blueprints)
-	} else {
-		db.Raw(`
-			SELECT *
-			FROM blueprints b
-			`+ordering+`
-			OFFSET ?
-			LIMIT ?
-		`, offset, limit).Scan(&blueprints)
-	}
-
-	return blueprints
+const blueprintHotnessQuery = `(
+	SELECT round(
+		CAST(
+			sign(scoring.score) * log(10, greatest(abs(scoring.score), 1))
+			+
+			((EXTRACT(epoch FROM b.created_at) - 1400000000) / 45000)
+			AS numeric
+		),
+		7
+	)
+	FROM (SELECT ` + blueprintScoreQuery + ` AS score) AS scoring
+)`
+
+var blueprintOrders = map[string]blueprintOrder{
+	"NEW":           {"b.created_at", "timestamp with time zone", 0},
+	"TOP":           {blueprintScoreQuery, "numeric", 0},
+	"POPULAR":       {blueprintHotnessQuery, "numeric", 0},
+	"BEST":          {blueprintBestQuery, "numeric", 0},
+	"TRENDING":      {blueprintTrendingQuery, "bigint", 2},
+	"CONTROVERSIAL": {blueprintControversialQuery, "numeric", 0},
+	"RELEVANCE":     {"ts_rank(b.search_vector, query)", "real", 0},
+}
+
+// CountBlueprints counts all blueprints matching the search query and filter
+func CountBlueprints(query string, filter BlueprintFilter) int {
+	from, where, args := blueprintSource(SearchQuery(query), filter)
+
+	var count struct {
+		Count int
+	}
+
+	db.Raw(`SELECT count(*) AS count FROM `+from+` `+where, args...).Scan(&count)
+
+	return count.Count
+}
+
+// ListBlueprints returns a page of blueprints matching the search query and
+// filter. Pages start at the cursor if given and at the offset otherwise.
+// NORMAL orders by relevance when searching and by creation date otherwise.
+func ListBlueprints(query string, filter BlueprintFilter, order string, ascending bool, page Page) (*BlueprintPage, error) {
+	tsQuery := SearchQuery(query)
+
+	if order == "NORMAL" || (order == "RELEVANCE" && tsQuery == "") {
+		order = "NEW"
+
+		if tsQuery != "" {
+			order = "RELEVANCE"
+		}
+	}
+
+	sort, ok := blueprintOrders[order]
+
+	if !ok {
+		return nil, ErrInvalidOrder
+	}
+
+	cursor := page.Cursor
+
+	if cursor != nil && (cursor.Order != order || cursor.Ascending != ascending) {
+		return nil, ErrInvalidCursor
+	}
+
+	selection := "b.*"
+
+	if tsQuery != "" {
+		selection += ", " + searchColumns
+	}
+
+	from, where, sourceArgs := blueprintSource(tsQuery, filter)
+
+	// Pages of an order depending on the current time continue at the time
+	// of the first page
+	var reference int64
+
+	if sort.references > 0 {
+		reference = time.Now().Unix()
+
+		if cursor != nil && cursor.Time != 0 {
+			reference = cursor.Time
+		}
+	}
+
+	var args []interface{}
+
+	for i := 0; i < sort.references; i++ {
+		args = append(args, time.Unix(reference, 0))
+	}
+
+	args = append(args, sourceArgs...)
+
+	// Pages ending at a cursor are fetched in reverse and flipped afterwards
+	before := cursor != nil && cursor.Before
+	descending := ascending == before
+
+	comparison, direction := ">", "ASC"
+
+	if descending {
+		comparison, direction = "<", "DESC"
+	}
+
+	var seek, skip string
+
+	if cursor != nil {
+		seek = "WHERE (b.sort_key, b.id) " + comparison + " (CAST(? AS " + sort.keyType + "), ?)"
+		args = append(args, cursor.Key, cursor.ID)
+	} else if page.Offset > 0 {
+		skip = "OFFSET ?"
+		args = append(args, page.Offset)
+	}
+
+	// One extra row tells whether there is another page
+	args = append(args, page.Limit+1)
+
+	var blueprints []*BlueprintSearchResult
+	err := db.Raw(`
+		SELECT b.*, CAST(b.sort_key AS text) AS cursor_key
+		FROM (
+			SELECT `+selection+`, `+sort.key+` AS sort_key
+			FROM `+from+`
+			`+where+`
+		) b
+		`+seek+`
+		ORDER BY b.sort_key `+direction+`, b.id `+direction+`
+		`+skip+`
+		LIMIT ?
+	`, args...).Scan(&blueprints).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	more := len(blueprints) > page.Limit
+
+	if more {
+		blueprints = blueprints[:page.Limit]
+	}
+
+	if before {
+		for i, j := 0, len(blueprints)-1; i < j; i, j = i+1, j-1 {
+			blueprints[i], blueprints[j] = blueprints[j], blueprints[i]
+		}
+	}
+
+	result := &BlueprintPage{
+		Blueprints: blueprints,
+		order:      order,
+		ascending:  ascending,
+		reference:  reference,
+	}
+
+	if len(blueprints) == 0 {
+		return result, nil
+	}
+
+	if (before && more) || (!before && (cursor != nil || page.Offset > 0)) {
+		result.Prev = result.Cursor(0)
+		result.Prev.Before = true
+	}
+
+	if before || more {
+		result.Next = result.Cursor(len(blueprints) - 1)
+	}
+
+	return result, nil
 }
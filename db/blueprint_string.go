@@ -0,0 +1,62 @@
+package db
+
+// BlueprintString counts the revisions referencing a blueprint string, the
+// string itself is kept in storage under its checksum
+type BlueprintString struct {
+	Checksum string `gorm:"primary_key"`
+	RefCount int    `gorm:"not null"`
+}
+
+type refCount struct {
+	RefCount int
+}
+
+func blueprintStringMigrations() {
+	fresh := !db.HasTable(&BlueprintString{})
+
+	db.AutoMigrate(&BlueprintString{})
+
+	// Identical strings may now be shared by several revisions
+	db.Model(&Revision{}).RemoveIndex("uix_revisions_blueprint_checksum")
+
+	if fresh {
+		db.Exec(`
+			INSERT INTO blueprint_strings (checksum, ref_count)
+			SELECT blueprint_checksum, count(*)
+			FROM revisions
+			WHERE deleted_at IS NULL
+			GROUP BY blueprint_checksum
+		`)
+	}
+}
+
+// AcquireBlueprintString adds a reference to the string and returns the new
+// number of references, one meaning the string is not stored yet
+func AcquireBlueprintString(checksum string) int {
+	var count refCount
+	db.Raw(`
+		INSERT INTO blueprint_strings (checksum, ref_count)
+		VALUES (?, 1)
+		ON CONFLICT (checksum) DO UPDATE SET ref_count = blueprint_strings.ref_count + 1
+		RETURNING ref_count
+	`, checksum).Scan(&count)
+	return count.RefCount
+}
+
+// ReleaseBlueprintString removes a reference to the string and returns the
+// number of references left, zero meaning the string can be removed
+func ReleaseBlueprintString(checksum string) int {
+	var count refCount
+	db.Raw(`
+		UPDATE blueprint_strings
+		SET ref_count = ref_count - 1
+		WHERE checksum = ?
+		RETURNING ref_count
+	`, checksum).Scan(&count)
+
+	if count.RefCount <= 0 {
+		db.Exec(`DELETE FROM blueprint_strings WHERE checksum = ? AND ref_count <= 0`, checksum)
+	}
+
+	return count.RefCount
+}
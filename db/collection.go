@@ -0,0 +1,128 @@
+package db
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// Listed on the profile of the owner
+	CollectionPublic = "public"
+
+	// Only reachable by id
+	CollectionUnlisted = "unlisted"
+
+	// Only visible to the owner
+	CollectionPrivate = "private"
+)
+
+var CollectionVisibilities = []string{CollectionPublic, CollectionUnlisted, CollectionPrivate}
+
+// Collection is an ordered list of blueprints curated by a user, blueprints
+// of other users included
+type Collection struct {
+	gorm.Model
+
+	UserID      uint   `gorm:"index;not null"`
+	Title       string `gorm:"not null"`
+	Description string `gorm:"not null"`
+	Visibility  string `gorm:"not null"`
+}
+
+// CollectionEntry places a blueprint in a collection, entries are replaced
+// as a whole
+type CollectionEntry struct {
+	gorm.Model
+
+	CollectionID uint `gorm:"not null;unique_index:idx_col_bp"`
+	BlueprintID  uint `gorm:"not null;unique_index:idx_col_bp;index"`
+	Position     int  `gorm:"not null"`
+}
+
+func collectionMigrations() {
+	db.AutoMigrate(&Collection{})
+	db.AutoMigrate(&CollectionEntry{})
+}
+
+func GetCollectionById(id uint) *Collection {
+	var collection Collection
+	db.Where("id = ?", id).Find(&collection)
+	if collection.ID != 0 {
+		return &collection
+	}
+	return nil
+}
+
+// GetUserCollections of the user, newest first. Only public collections are
+// returned unless private is set.
+func GetUserCollections(user uint, private bool) []*Collection {
+	var collections []*Collection
+	q := db.Where("user_id = ?", user)
+	if !private {
+		q = q.Where("visibility = ?", CollectionPublic)
+	}
+	q.Order("created_at desc").Find(&collections)
+	return collections
+}
+
+func (m *Collection) Save() {
+	db.Save(m)
+}
+
+func (m *Collection) Delete() {
+	db.Unscoped().Where("collection_id = ?", m.ID).Delete(CollectionEntry{})
+	db.Delete(m)
+}
+
+// VisibleTo reports whether the user, which may be nil, can see the collection
+func (m Collection) VisibleTo(user *User) bool {
+	return m.Visibility != CollectionPrivate || (user != nil && user.ID == m.UserID)
+}
+
+func (m Collection) GetUser() User {
+	var user User
+	db.Where("id = ?", m.UserID).Find(&user)
+	return user
+}
+
+// GetBlueprints in collection order, deleted blueprints are skipped
+func (m Collection) GetBlueprints() []*Blueprint {
+	var blueprints []*Blueprint
+	db.Raw(`
+		SELECT b.*
+		FROM collection_entries ce
+		JOIN blueprints b ON (b.id = ce.blueprint_id)
+		WHERE ce.collection_id = ? AND b.deleted_at IS NULL
+		ORDER BY ce.position
+	`, m.ID).Scan(&blueprints)
+	return blueprints
+}
+
+func (m Collection) CountBlueprints() int {
+	var count struct {
+		Count int
+	}
+	db.Raw(`
+		SELECT count(*) AS count
+		FROM collection_entries ce
+		JOIN blueprints b ON (b.id = ce.blueprint_id)
+		WHERE ce.collection_id = ? AND b.deleted_at IS NULL
+	`, m.ID).Scan(&count)
+	return count.Count
+}
+
+// SetBlueprints replaces the entries with the blueprints in the given order
+func (m Collection) SetBlueprints(blueprints []uint) {
+	tx := db.Begin()
+
+	tx.Unscoped().Where("collection_id = ?", m.ID).Delete(CollectionEntry{})
+
+	for i, blueprint := range blueprints {
+		tx.Create(&CollectionEntry{
+			CollectionID: m.ID,
+			BlueprintID:  blueprint,
+			Position:     i,
+		})
+	}
+
+	tx.Commit()
+}
@@ -1,15 +1,24 @@
 package db
 
 import (
+	"time"
+
 	"github.com/jinzhu/gorm"
 )
 
+// Replies can be nested this many levels below top level comments
+const MaxCommentDepth = 5
+
 type Comment struct {
 	gorm.Model
 
 	RevisionID uint     `gorm:"index; not null"`
 	UserID     uint     `gorm:"index; not null"`
 	Message    string   `gorm:"not null"`
+
+	// Comment replied to, replies share the revision of their parent
+	ParentID *uint `gorm:"index"`
+	Depth    int   `gorm:"not null" sql:"DEFAULT:0"`
 }
 
 func (m *Comment) Save() {
@@ -40,3 +49,87 @@ func (m Comment) GetRevision() Revision {
 	db.Where("id = ?", m.RevisionID).Find(&revision)
 	return revision
 }
+
+// GetReplies lists the direct replies, oldest first
+func (m Comment) GetReplies() []*Comment {
+	var comments []*Comment
+	db.Where("parent_id = ?", m.ID).Order("created_at").Find(&comments)
+	return comments
+}
+
+func (m Comment) CountReplies() int {
+	var count int
+	db.Model(&Comment{}).Where("parent_id = ?", m.ID).Count(&count)
+	return count
+}
+
+type commentReplies struct {
+	ParentID uint
+	Count    int
+}
+
+// CountCommentReplies counts the direct replies of each comment at once,
+// comments without replies are missing
+func CountCommentReplies(ids []uint) map[uint]int {
+	counts := make(map[uint]int)
+
+	if len(ids) == 0 {
+		return counts
+	}
+
+	var replies []commentReplies
+	db.Model(&Comment{}).
+		Select("parent_id, count(*) AS count").
+		Where("parent_id IN (?)", ids).
+		Group("parent_id").
+		Scan(&replies)
+
+	for _, r := range replies {
+		counts[r.ParentID] = r.Count
+	}
+
+	return counts
+}
+
+// CommentFilter restricts the comments listed for a blueprint, zero values
+// match everything
+type CommentFilter struct {
+	// Revision number within the blueprint
+	Revision uint
+	Author   uint
+	Since    *time.Time
+
+	// Only comments that are not replies, replies to deleted comments included
+	TopLevel bool
+}
+
+// GetComments lists the comments on all revisions of the blueprint, oldest
+// first. Comments on deleted revisions are skipped.
+func (m Blueprint) GetComments(filter CommentFilter) []*Comment {
+	var comments []*Comment
+
+	q := db.Table("comments c").
+		Select("c.*").
+		Joins("JOIN revisions r ON (r.id = c.revision_id)").
+		Where("r.blueprint_id = ? AND r.deleted_at IS NULL AND c.deleted_at IS NULL", m.ID)
+
+	if filter.Revision != 0 {
+		q = q.Where("r.revision = ?", filter.Revision)
+	}
+
+	if filter.Author != 0 {
+		q = q.Where("c.user_id = ?", filter.Author)
+	}
+
+	if filter.Since != nil {
+		q = q.Where("c.created_at >= ?", *filter.Since)
+	}
+
+	if filter.TopLevel {
+		q = q.Where("c.parent_id IS NULL OR NOT EXISTS (SELECT 1 FROM comments p WHERE p.id = c.parent_id AND p.deleted_at IS NULL)")
+	}
+
+	q.Order("c.created_at, c.id").Scan(&comments)
+
+	return comments
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidOrder  = errors.New("invalid order")
+)
+
+// Cursor marks a position within a listing by the sort key and id of a
+// blueprint. Pages continue after it, or end right before it if Before is set.
+type Cursor struct {
+	Order     string `json:"o"`
+	Ascending bool   `json:"a,omitempty"`
+	Key       string `json:"k"`
+	ID        uint   `json:"i"`
+	Before    bool   `json:"b,omitempty"`
+
+	// Reference time of orders depending on the current time, unix seconds
+	Time int64 `json:"t,omitempty"`
+}
+
+// Encode returns the cursor as an opaque url safe token
+func (c *Cursor) Encode() string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func DecodeCursor(token string) (*Cursor, error) {
+	js, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(js, &cursor); err != nil || cursor.Order == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+type Page struct {
+	Cursor *Cursor
+	Offset int
+	Limit  int
+}
+
+type BlueprintPage struct {
+	Blueprints []*BlueprintSearchResult
+	Next       *Cursor
+	Prev       *Cursor
+
+	order     string
+	ascending bool
+	reference int64
+}
+
+// Cursor pointing right after the i-th blueprint of the page
+func (p *BlueprintPage) Cursor(i int) *Cursor {
+	return &Cursor{
+		Order:     p.order,
+		Ascending: p.ascending,
+		Key:       p.Blueprints[i].CursorKey,
+		ID:        p.Blueprints[i].ID,
+		Time:      p.reference,
+	}
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestCursorEncodeDecode(t *testing.T) {
+	tests := []Cursor{
+		{Order: "NEW", Key: "2018-06-01T12:00:00Z", ID: 12},
+		{Order: "TOP", Ascending: true, Key: "-3", ID: 1, Before: true},
+		{Order: "TRENDING", Key: "0.25", ID: 4294967295, Time: 1527854400},
+		{Order: "NAME", Key: "\"quoted\" / üñíçødé", ID: 7},
+	}
+
+	for _, test := range tests {
+		token := test.Encode()
+
+		cursor, err := DecodeCursor(token)
+		if err != nil {
+			t.Errorf("DecodeCursor(%q): %v", token, err)
+			continue
+		}
+
+		if *cursor != test {
+			t.Errorf("DecodeCursor(%q): got %+v, want %+v", token, *cursor, test)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"not base64", "not a cursor!"},
+		{"padded", base64.URLEncoding.EncodeToString([]byte(`{"o":"NEW","k":"1","i":1}`))},
+		{"not json", encode("NEW 1 1")},
+		{"wrong type", encode(`{"o":"NEW","k":"1","i":"1"}`)},
+		{"no order", encode(`{"k":"1","i":1}`)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := DecodeCursor(test.token); err != ErrInvalidCursor {
+				t.Errorf("DecodeCursor(%q): got error %v, want %v", test.token, err, ErrInvalidCursor)
+			}
+		})
+	}
+}
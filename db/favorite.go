@@ -0,0 +1,81 @@
+package db
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// Favorite bookmarks a blueprint for a user across all its revisions
+type Favorite struct {
+	gorm.Model
+
+	UserID      uint `gorm:"index;not null;unique_index:idx_uid_bp"`
+	BlueprintID uint `gorm:"index;not null;unique_index:idx_uid_bp"`
+}
+
+func (m *Favorite) Save() {
+	db.Unscoped().Save(m)
+}
+
+func (m *Favorite) Delete() {
+	db.Delete(m)
+}
+
+// FindFavorite includes removed favorites so they can be restored
+func FindFavorite(userId uint, blueprintId uint) Favorite {
+	var favorite Favorite
+	db.Unscoped().Where("user_id = ? AND blueprint_id = ?", userId, blueprintId).Limit(1).Find(&favorite)
+	return favorite
+}
+
+func IsFavorite(userId uint, blueprintId uint) bool {
+	var count int
+	db.Model(&Favorite{}).Where("user_id = ? AND blueprint_id = ?", userId, blueprintId).Count(&count)
+	return count > 0
+}
+
+func (m Blueprint) CountFavorites() int {
+	var count int
+	db.Model(&Favorite{}).Where("blueprint_id = ?", m.ID).Count(&count)
+	return count
+}
+
+type blueprintFavorites struct {
+	BlueprintID uint
+	Count       int
+}
+
+// CountBlueprintFavorites counts the favorites of each blueprint at once,
+// blueprints nobody favorited are missing
+func CountBlueprintFavorites(ids []uint) map[uint]int {
+	counts := make(map[uint]int)
+
+	if len(ids) == 0 {
+		return counts
+	}
+
+	var favorites []blueprintFavorites
+	db.Model(&Favorite{}).
+		Select("blueprint_id, count(*) AS count").
+		Where("blueprint_id IN (?)", ids).
+		Group("blueprint_id").
+		Scan(&favorites)
+
+	for _, f := range favorites {
+		counts[f.BlueprintID] = f.Count
+	}
+
+	return counts
+}
+
+// GetFavoriteBlueprints of the user, most recently favorited first
+func (m User) GetFavoriteBlueprints() []*Blueprint {
+	var blueprints []*Blueprint
+	db.Raw(`
+		SELECT b.*
+		FROM favorites f
+		JOIN blueprints b ON (b.id = f.blueprint_id)
+		WHERE f.user_id = ? AND f.deleted_at IS NULL AND b.deleted_at IS NULL
+		ORDER BY f.updated_at DESC
+	`, m.ID).Scan(&blueprints)
+	return blueprints
+}
@@ -17,8 +17,45 @@ func migrations() {
 	db.AutoMigrate(&Blueprint{})
 	db.AutoMigrate(&Comment{})
 	db.AutoMigrate(&Rating{})
+	db.AutoMigrate(&Favorite{})
 	db.AutoMigrate(&Tag{})
 	db.AutoMigrate(&BlueprintTag{})
 	db.AutoMigrate(&User{})
 	db.AutoMigrate(&Revision{})
+	db.AutoMigrate(&RevisionMetadata{})
+	db.AutoMigrate(&RevisionEntity{})
+
+	searchMigrations()
+	blueprintStringMigrations()
+	renderJobMigrations()
+	collectionMigrations()
+	scoreMigrations()
+}
+
+// WithLock runs fn while holding the Postgres advisory lock of the key so it
+// does not run on several servers at once. It returns false without running
+// fn if the lock is held elsewhere.
+func WithLock(key int64, fn func() error) (bool, error) {
+	tx := db.Begin()
+
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+
+	// The lock is released with the transaction
+	defer tx.Rollback()
+
+	var lock struct {
+		Locked bool
+	}
+
+	if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?) AS locked", key).Scan(&lock).Error; err != nil {
+		return false, err
+	}
+
+	if !lock.Locked {
+		return false, nil
+	}
+
+	return true, fn()
 }
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"github.com/BlooperDB/API/blueprint"
+	"github.com/jinzhu/gorm"
+)
+
+// RevisionMetadata is derived from the blueprint string of a revision so
+// listings can filter on it without fetching strings from storage
+type RevisionMetadata struct {
+	gorm.Model
+
+	RevisionID        uint   `gorm:"not null;unique_index"`
+	Width             int    `gorm:"not null;index"`
+	Height            int    `gorm:"not null;index"`
+	GameVersion       string `gorm:"not null;index"`
+	GameVersionNumber int64  `gorm:"not null"`
+	Modded            bool   `gorm:"not null"`
+	EntityCount       int    `gorm:"not null"`
+	TileCount         int    `gorm:"not null"`
+}
+
+func (RevisionMetadata) TableName() string {
+	return "revision_metadata"
+}
+
+// RevisionEntity is a single bucket of the entity histogram of a revision
+type RevisionEntity struct {
+	gorm.Model
+
+	RevisionID uint   `gorm:"not null;unique_index:idx_rev_entity"`
+	Name       string `gorm:"not null;unique_index:idx_rev_entity;index"`
+	Count      int    `gorm:"not null"`
+}
+
+// IndexRevision replaces the metadata and entities of the revision, nothing
+// is changed if it fails
+func IndexRevision(revision *Revision, container *blueprint.Container) error {
+	materials := container.Materials()
+
+	metadata := RevisionMetadata{
+		RevisionID:        revision.ID,
+		GameVersion:       container.Version().String(),
+		GameVersionNumber: int64(container.Version()),
+	}
+
+	for name, count := range materials.Entities {
+		metadata.EntityCount += count
+
+		if !blueprint.IsVanillaEntity(name) {
+			metadata.Modded = true
+		}
+	}
+
+	for _, count := range materials.Tiles {
+		metadata.TileCount += count
+	}
+
+	for _, bp := range containerBlueprints(container) {
+		bounds := bp.Bounds()
+
+		if bounds.Width() > metadata.Width {
+			metadata.Width = bounds.Width()
+		}
+
+		if bounds.Height() > metadata.Height {
+			metadata.Height = bounds.Height()
+		}
+	}
+
+	tx := db.Begin()
+
+	if err := tx.Unscoped().Where("revision_id = ?", revision.ID).Delete(RevisionMetadata{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Unscoped().Where("revision_id = ?", revision.ID).Delete(RevisionEntity{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Create(&metadata).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for name, count := range materials.Entities {
+		err := tx.Create(&RevisionEntity{
+			RevisionID: revision.ID,
+			Name:       name,
+			Count:      count,
+		}).Error
+
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// Blueprints of a container, books yield the blueprints of all their entries
+// including those of nested books
+func containerBlueprints(container *blueprint.Container) []*blueprint.Blueprint {
+	if container.Blueprint != nil {
+		return []*blueprint.Blueprint{container.Blueprint}
+	}
+
+	var blueprints []*blueprint.Blueprint
+
+	if container.BlueprintBook != nil {
+		for i := range container.BlueprintBook.Blueprints {
+			blueprints = append(blueprints, containerBlueprints(&container.BlueprintBook.Blueprints[i].Container)...)
+		}
+	}
+
+	return blueprints
+}
+
+func GetRevisionMetadata(revisionId uint) *RevisionMetadata {
+	var metadata RevisionMetadata
+	db.Where("revision_id = ?", revisionId).Find(&metadata)
+	if metadata.ID != 0 {
+		return &metadata
+	}
+	return nil
+}
+
+func GetRevisionEntities(revisionId uint) []*RevisionEntity {
+	var entities []*RevisionEntity
+	db.Where("revision_id = ?", revisionId).Order("count desc, name").Find(&entities)
+	return entities
+}
+
+func FindUnindexedRevisions() []*Revision {
+	var revisions []*Revision
+	db.Where("id NOT IN (SELECT revision_id FROM revision_metadata)").Find(&revisions)
+	return revisions
+}
@@ -12,12 +12,46 @@ type Rating struct {
 	ThumbsUp   bool `gorm:"not null"`
 }
 
-func (m *Rating) Save() {
-	db.Unscoped().Save(m)
+func (m *Rating) Save() error {
+	return changeRating(m.RevisionID, func(tx *gorm.DB) error {
+		return tx.Unscoped().Save(m).Error
+	})
 }
 
-func (m *Rating) Delete() {
-	db.Delete(m)
+func (m *Rating) Delete() error {
+	return changeRating(m.RevisionID, func(tx *gorm.DB) error {
+		return tx.Delete(m).Error
+	})
+}
+
+// changeRating applies the change to a rating of the revision and updates the
+// score of its blueprint in the same transaction, nothing is changed if
+// either fails
+func changeRating(revisionID uint, change func(tx *gorm.DB) error) error {
+	tx := db.Begin()
+
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	var revision Revision
+
+	if err := tx.Where("id = ?", revisionID).First(&revision).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := change(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := updateBlueprintScore(tx, revision.BlueprintID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
 }
 
 func (m Rating) GetUser() User {
@@ -0,0 +1,227 @@
+package db
+
+import (
+	"math"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	RenderJobQueued  = "queued"
+	RenderJobRunning = "running"
+	RenderJobFailed  = "failed"
+	RenderJobDone    = "done"
+)
+
+// RenderJob tracks rendering the images of a revision. Failed attempts are
+// retried with exponential backoff until the maximum attempts are reached.
+// Workers beat while rendering, running jobs without a recent heartbeat are
+// considered abandoned.
+type RenderJob struct {
+	gorm.Model
+
+	RevisionID  uint       `gorm:"not null;unique_index"`
+	Status      string     `gorm:"not null;index"`
+	Attempts    int        `gorm:"not null"`
+	RunAt       time.Time  `gorm:"not null;index"`
+	Error       string     `gorm:"not null"`
+	FinishedAt  *time.Time `gorm:"null"`
+	HeartbeatAt *time.Time `gorm:"null"`
+}
+
+func renderJobMigrations() {
+	db.AutoMigrate(&RenderJob{})
+
+	// Revisions used to track renders with a single flag
+	if db.Dialect().HasColumn("revisions", "rendered") {
+		db.Exec(`
+			INSERT INTO render_jobs (created_at, updated_at, revision_id, status, attempts, run_at, error, finished_at)
+			SELECT now(), now(), id, CASE WHEN rendered THEN ? ELSE ? END, 0, now(), '', CASE WHEN rendered THEN now() END
+			FROM revisions
+			WHERE deleted_at IS NULL
+			ON CONFLICT (revision_id) DO NOTHING
+		`, RenderJobDone, RenderJobQueued)
+
+		db.Model(&Revision{}).DropColumn("rendered")
+	}
+}
+
+func (m *RenderJob) Save() {
+	db.Save(m)
+}
+
+// QueueRenderJob queues the revision for rendering, resetting any previous job
+func QueueRenderJob(revision uint) {
+	db.Exec(`
+		INSERT INTO render_jobs (created_at, updated_at, revision_id, status, attempts, run_at, error)
+		VALUES (now(), now(), ?, ?, 0, now(), '')
+		ON CONFLICT (revision_id) DO UPDATE
+		SET updated_at = now(), status = EXCLUDED.status, attempts = 0, run_at = now(), error = '', finished_at = NULL
+	`, revision, RenderJobQueued)
+}
+
+func GetRenderJob(revision uint) *RenderJob {
+	var job RenderJob
+	db.Where("revision_id = ?", revision).Find(&job)
+	if job.ID != 0 {
+		return &job
+	}
+	return nil
+}
+
+// GetRenderJobs returns the jobs of the revisions by revision id, revisions
+// without a job are missing
+func GetRenderJobs(revisions []uint) map[uint]*RenderJob {
+	jobs := make(map[uint]*RenderJob)
+
+	if len(revisions) == 0 {
+		return jobs
+	}
+
+	var found []*RenderJob
+	db.Where("revision_id IN (?)", revisions).Find(&found)
+
+	for _, job := range found {
+		jobs[job.RevisionID] = job
+	}
+
+	return jobs
+}
+
+// ClaimRenderJob marks the next due job as running and returns it, jobs
+// claimed by other workers are skipped. Only jobs of the revisions are
+// claimed unless there are none.
+func ClaimRenderJob(revisions []uint) *RenderJob {
+	filter := ""
+	args := []interface{}{RenderJobRunning, RenderJobQueued}
+
+	if len(revisions) > 0 {
+		filter = "AND revision_id IN (?)"
+		args = append(args, revisions)
+	}
+
+	var job RenderJob
+	db.Raw(`
+		UPDATE render_jobs
+		SET status = ?, attempts = attempts + 1, updated_at = now(), heartbeat_at = now()
+		WHERE id = (
+			SELECT id
+			FROM render_jobs
+			WHERE status = ? AND run_at <= now() AND deleted_at IS NULL `+filter+`
+			ORDER BY run_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`, args...).Scan(&job)
+	if job.ID != 0 {
+		return &job
+	}
+	return nil
+}
+
+// RequeueStaleRenderJobs queues jobs again whose worker has not sent a
+// heartbeat for longer than the timeout, it most likely died. The attempt was
+// counted when the job was claimed, jobs that reached maxAttempts fail for
+// good so a revision that kills its worker is not retried forever.
+func RequeueStaleRenderJobs(timeout time.Duration, maxAttempts int) {
+	stale := time.Now().Add(-timeout)
+
+	db.Exec(`
+		UPDATE render_jobs
+		SET status = ?, error = ?, finished_at = now(), updated_at = now()
+		WHERE status = ? AND COALESCE(heartbeat_at, updated_at) < ? AND attempts >= ?
+	`, RenderJobFailed, "render timed out", RenderJobRunning, stale, maxAttempts)
+
+	db.Exec(`
+		UPDATE render_jobs
+		SET status = ?, error = ?, run_at = now(), updated_at = now()
+		WHERE status = ? AND COALESCE(heartbeat_at, updated_at) < ?
+	`, RenderJobQueued, "render timed out", RenderJobRunning, stale)
+}
+
+// Heartbeat marks the claimed job as still being rendered, it returns false
+// once the job was requeued or claimed again by another worker
+func (m *RenderJob) Heartbeat() bool {
+	now := time.Now()
+
+	update := db.Model(&RenderJob{}).
+		Where("id = ? AND status = ? AND attempts = ?", m.ID, RenderJobRunning, m.Attempts).
+		Update("heartbeat_at", now)
+
+	if update.Error != nil || update.RowsAffected == 0 {
+		return false
+	}
+
+	m.HeartbeatAt = &now
+
+	return true
+}
+
+func (m *RenderJob) Complete() bool {
+	now := time.Now()
+	m.Status = RenderJobDone
+	m.Error = ""
+	m.FinishedAt = &now
+	return m.finish()
+}
+
+// Fail records the error and schedules a retry after backoff * 2^(attempts-1),
+// the job fails for good once maxAttempts is reached
+func (m *RenderJob) Fail(err error, maxAttempts int, backoff time.Duration) bool {
+	m.Error = err.Error()
+
+	if m.Attempts >= maxAttempts {
+		now := time.Now()
+		m.Status = RenderJobFailed
+		m.FinishedAt = &now
+	} else {
+		m.Status = RenderJobQueued
+		m.RunAt = time.Now().Add(backoff * time.Duration(math.Pow(2, float64(m.Attempts-1))))
+	}
+
+	return m.finish()
+}
+
+// finish records the outcome of the attempt unless the job has been claimed
+// again since, a worker that was considered dead must not overwrite the
+// outcome of a later attempt
+func (m *RenderJob) finish() bool {
+	update := db.Model(&RenderJob{}).
+		Where("id = ? AND attempts = ?", m.ID, m.Attempts).
+		Updates(map[string]interface{}{
+			"status":      m.Status,
+			"error":       m.Error,
+			"run_at":      m.RunAt,
+			"finished_at": m.FinishedAt,
+			"updated_at":  time.Now(),
+		})
+
+	return update.Error == nil && update.RowsAffected > 0
+}
+
+// QueueAllRenderJobs queues every revision for rendering again
+func QueueAllRenderJobs() {
+	db.Exec(`
+		INSERT INTO render_jobs (created_at, updated_at, revision_id, status, attempts, run_at, error)
+		SELECT now(), now(), id, ?, 0, now(), ''
+		FROM revisions
+		WHERE deleted_at IS NULL
+		ON CONFLICT (revision_id) DO UPDATE
+		SET updated_at = now(), status = EXCLUDED.status, attempts = 0, run_at = now(), error = '', finished_at = NULL
+	`, RenderJobQueued)
+}
+
+// FindDueRenderJobs returns all queued jobs that are due without claiming them
+func FindDueRenderJobs() []*RenderJob {
+	var jobs []*RenderJob
+	db.Where("status = ? AND run_at <= now()", RenderJobQueued).Order("run_at").Find(&jobs)
+	return jobs
+}
+
+func CountRenderJobs(status string) int {
+	var count int
+	db.Model(&RenderJob{}).Where("status = ?", status).Count(&count)
+	return count
+}
@@ -11,8 +11,8 @@ type Revision struct {
 	Revision          uint   `gorm:"not null;unique_index:idx_bp_rev"`
 	Changes           string `gorm:"not null"`
 	BlueprintVersion  int    `gorm:"not null" sql:"type:int4; DEFAULT:0"`
-	BlueprintChecksum string `gorm:"not null;unique_index"`
-	Rendered          bool   `gorm:"not null" sql:"type:boolean; DEFAULT:false"`
+	BlueprintChecksum string `gorm:"not null;index"`
+	Kind              string `gorm:"not null" sql:"DEFAULT:'blueprint'"`
 }
 
 func (m *Revision) Save() {
@@ -23,9 +23,21 @@ func (m *Revision) Delete() {
 	db.Delete(m)
 }
 
+// GetComments lists all comments including replies, oldest first
 func (m Revision) GetComments() []*Comment {
 	var comments []*Comment
-	db.Where("revision_id = ?", m.ID).Find(&comments)
+	db.Where("revision_id = ?", m.ID).Order("created_at").Find(&comments)
+	return comments
+}
+
+// GetTopLevelComments lists the comments that are not replies, oldest first.
+// Replies to deleted comments are top level.
+func (m Revision) GetTopLevelComments() []*Comment {
+	var comments []*Comment
+	db.Where("revision_id = ?", m.ID).
+		Where("parent_id IS NULL OR NOT EXISTS (SELECT 1 FROM comments p WHERE p.id = comments.parent_id AND p.deleted_at IS NULL)").
+		Order("created_at").
+		Find(&comments)
 	return comments
 }
 
@@ -35,6 +47,10 @@ func (m Revision) GetRatings() []Rating {
 	return ratings
 }
 
+func (m Revision) GetRenderJob() *RenderJob {
+	return GetRenderJob(m.ID)
+}
+
 func (m Revision) GetBlueprint() Blueprint {
 	var blueprint Blueprint
 	db.Where("id = ?", m.BlueprintID).Find(&blueprint)
@@ -50,6 +66,16 @@ func GetRevisionById(id uint) *Revision {
 	return nil
 }
 
+// GetRevisionByIdUnscoped also finds deleted revisions
+func GetRevisionByIdUnscoped(id uint) *Revision {
+	var revision Revision
+	db.Unscoped().Where("id = ?", id).Find(&revision)
+	if revision.ID != 0 {
+		return &revision
+	}
+	return nil
+}
+
 func FindRevisionByChecksum(checksum string) *Revision {
 	var revision Revision
 	db.Where("blueprint_checksum = ?", checksum).Find(&revision)
@@ -59,10 +85,15 @@ func FindRevisionByChecksum(checksum string) *Revision {
 	return nil
 }
 
-func FindUnrenderedRevisions() []*Revision {
-	var revisions []*Revision
-	db.Where("rendered = ?", false).Find(&revisions)
-	return revisions
+// FindBlueprintRevisionByChecksum finds a revision of the blueprint with the
+// same string, other blueprints may share it
+func FindBlueprintRevisionByChecksum(blueprint uint, checksum string) *Revision {
+	var revision Revision
+	db.Where("blueprint_id = ? AND blueprint_checksum = ?", blueprint, checksum).Find(&revision)
+	if revision.ID != 0 {
+		return &revision
+	}
+	return nil
 }
 
 func FindLatestRevisionFromBlueprint(blueprint uint) *Revision {
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ScoreDecay weighs the ratings of a revision by ScoreDecay^n where n is the
+// number of revisions posted after it, 1 counts all revisions equally
+var ScoreDecay = 1.0
+
+// BlueprintScore aggregates the ratings of all revisions of a blueprint, it is
+// updated in the same transaction as every rating change
+type BlueprintScore struct {
+	BlueprintID uint      `gorm:"primary_key;auto_increment:false"`
+	ThumbsUp    int       `gorm:"not null"`
+	ThumbsDown  int       `gorm:"not null"`
+	Score       float64   `gorm:"not null;index"`
+	UpdatedAt   time.Time `gorm:"not null"`
+}
+
+// Recomputes the scores of the blueprints matching the condition on "b"
+const blueprintScoreUpsert = `
+	INSERT INTO blueprint_scores (blueprint_id, thumbs_up, thumbs_down, score, updated_at)
+	SELECT
+		b.id,
+		COUNT(ra.id) FILTER (WHERE ra.thumbs_up = true),
+		COUNT(ra.id) FILTER (WHERE ra.thumbs_up = false),
+		COALESCE(SUM(
+			CASE WHEN ra.thumbs_up = true THEN 1 WHEN ra.thumbs_up = false THEN -1 ELSE 0 END
+			*
+			power(CAST(? AS double precision), greatest(b.last_revision - r.revision, 0))
+		), 0),
+		now()
+	FROM blueprints b
+	LEFT JOIN revisions r ON (r.blueprint_id = b.id AND r.deleted_at IS NULL)
+	LEFT JOIN ratings ra ON (ra.revision_id = r.id AND ra.deleted_at IS NULL)
+	WHERE `
+
+const blueprintScoreUpdate = `
+	GROUP BY b.id
+	ON CONFLICT (blueprint_id) DO UPDATE
+	SET thumbs_up = EXCLUDED.thumbs_up, thumbs_down = EXCLUDED.thumbs_down, score = EXCLUDED.score, updated_at = EXCLUDED.updated_at
+`
+
+// Score of a blueprint for sorting, blueprints without a score row count as 0.
+// Rounded so the key survives the text round trip of cursors.
+const blueprintScoreQuery = `round(CAST(COALESCE((
+	SELECT score
+	FROM blueprint_scores
+	WHERE blueprint_id = b.id
+), 0) AS numeric), 7)`
+
+// Thumbs up and down of a blueprint as "up" and "down", 0 without a score row
+const blueprintVotesQuery = `(
+	SELECT
+		CAST(COALESCE(MAX(thumbs_up), 0) AS double precision) AS up,
+		CAST(COALESCE(MAX(thumbs_down), 0) AS double precision) AS down
+	FROM blueprint_scores
+	WHERE blueprint_id = b.id
+)`
+
+// Lower bound of the 95% Wilson score interval of the share of thumbs up, a
+// few votes are less certain than many
+const blueprintBestQuery = `(
+	SELECT round(
+		CAST(
+			CASE WHEN v.up + v.down = 0 THEN 0 ELSE
+				((v.up + 1.9208) / (v.up + v.down) - 1.96 * sqrt((v.up * v.down) / (v.up + v.down) + 0.9604) / (v.up + v.down))
+				/
+				(1 + 3.8416 / (v.up + v.down))
+			END
+			AS numeric
+		),
+		7
+	)
+	FROM ` + blueprintVotesQuery + ` AS v
+)`
+
+// Many votes split evenly between thumbs up and down rank highest
+const blueprintControversialQuery = `(
+	SELECT round(
+		CAST(
+			CASE WHEN v.up = 0 OR v.down = 0 THEN 0 ELSE
+				power(v.up + v.down, least(v.up, v.down) / greatest(v.up, v.down))
+			END
+			AS numeric
+		),
+		7
+	)
+	FROM ` + blueprintVotesQuery + ` AS v
+)`
+
+// Days of ratings counted by the TRENDING order
+const TrendingDays = 7
+
+// Thumbs up minus thumbs down given to any revision in the TrendingDays up to
+// the reference time, changed votes count from the time they were changed.
+// Both placeholders take the reference time, which cursors keep so later pages
+// rank the same votes.
+var blueprintTrendingQuery = `COALESCE((
+	SELECT SUM(CASE WHEN ra.thumbs_up = true THEN 1 ELSE -1 END)
+	FROM ratings ra
+	JOIN revisions r ON (r.id = ra.revision_id)
+	WHERE r.blueprint_id = b.id
+		AND r.deleted_at IS NULL
+		AND ra.deleted_at IS NULL
+		AND ra.updated_at > CAST(? AS timestamp with time zone) - interval '` + strconv.Itoa(TrendingDays) + ` days'
+		AND ra.updated_at <= CAST(? AS timestamp with time zone)
+), 0)`
+
+// Blueprints rated before scores existed are scored once
+func scoreMigrations() {
+	db.AutoMigrate(&BlueprintScore{})
+	db.Exec(blueprintScoreUpsert+`b.deleted_at IS NULL GROUP BY b.id ON CONFLICT (blueprint_id) DO NOTHING`, ScoreDecay)
+}
+
+// RefreshBlueprintScores recomputes the scores of all blueprints, needed after
+// changing ScoreDecay
+func RefreshBlueprintScores() {
+	db.Exec(blueprintScoreUpsert+`b.deleted_at IS NULL`+blueprintScoreUpdate, ScoreDecay)
+}
+
+// UpdateBlueprintScore recomputes the score of the blueprint
+func UpdateBlueprintScore(id uint) error {
+	tx := db.Begin()
+
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if err := updateBlueprintScore(tx, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// updateBlueprintScore locks the blueprint so concurrent rating changes are
+// applied one after another and each sees the ratings of the others
+func updateBlueprintScore(tx *gorm.DB, id uint) error {
+	if err := tx.Exec(`SELECT id FROM blueprints WHERE id = ? FOR UPDATE`, id).Error; err != nil {
+		return err
+	}
+
+	return tx.Exec(blueprintScoreUpsert+`b.id = ?`+blueprintScoreUpdate, ScoreDecay, id).Error
+}
+
+func GetBlueprintScore(id uint) BlueprintScore {
+	var score BlueprintScore
+	db.Where("blueprint_id = ?", id).Limit(1).Find(&score)
+	score.BlueprintID = id
+	return score
+}
+
+func (m Blueprint) GetScore() BlueprintScore {
+	return GetBlueprintScore(m.ID)
+}
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"strings"
+	"unicode"
+)
+
+// The search vector weighs blueprint names highest, followed by tags,
+// descriptions and finally the changelogs of all revisions
+const searchVectorQuery = `
+	setweight(to_tsvector('english', coalesce(b.name, '')), 'A')
+	||
+	setweight(to_tsvector('english', coalesce((
+		SELECT string_agg(t.name, ' ')
+		FROM tags t
+		JOIN blueprint_tags bt ON (t.id = bt.tag_id)
+		WHERE bt.blueprint_id = b.id AND bt.deleted_at IS NULL
+	), '')), 'B')
+	||
+	setweight(to_tsvector('english', coalesce(b.description, '')), 'C')
+	||
+	setweight(to_tsvector('english', coalesce((
+		SELECT string_agg(r.changes, ' ')
+		FROM revisions r
+		WHERE r.blueprint_id = b.id AND r.deleted_at IS NULL
+	), '')), 'D')
+`
+
+// Columns selected alongside a blueprint when searching, expects the
+// tsquery to be available as "query"
+const searchColumns = `
+	ts_rank(b.search_vector, query) AS rank,
+	ts_headline('english', b.name, query, 'HighlightAll=true') AS highlight_name,
+	ts_headline('english', b.description, query, 'MaxFragments=2, MinWords=5, MaxWords=20') AS highlight_description
+`
+
+type BlueprintSearchResult struct {
+	Blueprint
+
+	Rank                 float64
+	HighlightName        string
+	HighlightDescription string
+
+	// Sort key of the listing order as text, used to build cursors
+	CursorKey string
+}
+
+func searchMigrations() {
+	db.Exec(`ALTER TABLE blueprints ADD COLUMN IF NOT EXISTS search_vector tsvector`)
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_blueprints_search_vector ON blueprints USING GIN (search_vector)`)
+	db.Exec(`UPDATE blueprints b SET search_vector = ` + searchVectorQuery + ` WHERE b.search_vector IS NULL`)
+}
+
+// UpdateBlueprintSearch recomputes the search vector of the blueprint, needed
+// after its name, description, tags or the changes of its revisions changed
+func UpdateBlueprintSearch(id uint) {
+	db.Exec(`UPDATE blueprints b SET search_vector = `+searchVectorQuery+` WHERE b.id = ?`, id)
+}
+
+// SearchQuery turns user input into a tsquery matching every term as a
+// prefix, returns an empty string if there is nothing to search for
+func SearchQuery(query string) string {
+	terms := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, term := range terms {
+		terms[i] = term + ":*"
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+// blueprintSource returns the FROM and WHERE clauses selecting every
+// blueprint "b" matching the tsquery and filter
+func blueprintSource(tsQuery string, filter BlueprintFilter) (string, string, []interface{}) {
+	where := []string{"b.deleted_at IS NULL"}
+	var args []interface{}
+
+	from := "blueprints b"
+
+	if tsQuery != "" {
+		from += ", to_tsquery('english', ?) query"
+		where = append(where, "b.search_vector @@ query")
+		args = append(args, tsQuery)
+	}
+
+	if filterWhere, filterArgs := filter.where(); filterWhere != "" {
+		where = append(where, filterWhere)
+		args = append(args, filterArgs...)
+	}
+
+	return from, "WHERE " + strings.Join(where, " AND "), args
+}
+
+type FacetBucket struct {
+	Value string
+	Count int
+
+	// Set on buckets of records such as authors, the value is their name
+	ID uint
+}
+
+type Facets struct {
+	Tags         []FacetBucket
+	Authors      []FacetBucket
+	GameVersions []FacetBucket
+	Kinds        []FacetBucket
+}
+
+// SearchFacets counts tags, authors, game versions and kinds over every
+// blueprint matching the search query and filter
+func SearchFacets(query string, filter BlueprintFilter) *Facets {
+	from, where, args := blueprintSource(SearchQuery(query), filter)
+	results := `WITH results AS (SELECT b.id, b.user_id FROM ` + from + ` ` + where + `)`
+
+	var facets Facets
+
+	db.Raw(results+`
+		SELECT t.name AS value, count(*) AS count
+		FROM results b
+		JOIN blueprint_tags bt ON (bt.blueprint_id = b.id AND bt.deleted_at IS NULL)
+		JOIN tags t ON (t.id = bt.tag_id)
+		GROUP BY t.name
+		ORDER BY count DESC, value ASC
+		LIMIT 20
+	`, args...).Scan(&facets.Tags)
+
+	db.Raw(results+`
+		SELECT b.user_id AS id, COALESCE(u.username, '') AS value, count(*) AS count
+		FROM results b
+		JOIN users u ON (u.id = b.user_id)
+		GROUP BY b.user_id, u.username
+		ORDER BY count DESC, b.user_id ASC
+		LIMIT 10
+	`, args...).Scan(&facets.Authors)
+
+	db.Raw(results+`
+		SELECT rm.game_version AS value, count(*) AS count
+		FROM results b
+		JOIN revision_metadata rm ON (rm.revision_id = `+latestRevisionQuery+`)
+		GROUP BY rm.game_version
+		ORDER BY count DESC, value DESC
+		LIMIT 20
+	`, args...).Scan(&facets.GameVersions)
+
+	db.Raw(results+`
+		SELECT r.kind AS value, count(*) AS count
+		FROM results b
+		JOIN revisions r ON (r.id = `+latestRevisionQuery+`)
+		GROUP BY r.kind
+		ORDER BY count DESC, value ASC
+	`, args...).Scan(&facets.Kinds)
+
+	return &facets
+}
@@ -8,7 +8,9 @@ import (
 	"strconv"
 
 	"github.com/BlooperDB/API/api"
+	bp "github.com/BlooperDB/API/blueprint"
 	"github.com/BlooperDB/API/db"
+	"github.com/BlooperDB/API/render"
 	"github.com/BlooperDB/API/storage"
 	"github.com/BlooperDB/API/utils"
 	"github.com/gorilla/mux"
@@ -24,7 +26,25 @@ type BlueprintResponse struct {
 	Tags        []string    `json:"tags"`
 	CreatedAt   time.Time   `json:"created-at"`
 	UpdatedAt   time.Time   `json:"updated-at"`
-	Thumbnail   string      `json:"thumbnail"`
+	Thumbnail   string      `json:"thumbnail,omitempty"`
+	Highlight   *Highlight  `json:"highlight,omitempty"`
+
+	// Blueprint and global revision identifier this blueprint was forked from
+	ForkedFrom         *uint `json:"forked-from,omitempty"`
+	ForkedFromRevision *uint `json:"forked-from-revision,omitempty"`
+
+	Forks int `json:"forks"`
+
+	Favorites int `json:"favorites"`
+
+	// Only set when authenticated
+	Favorited *bool `json:"favorited,omitempty"`
+}
+
+// Highlight holds search snippets with matches wrapped in <b> tags
+type Highlight struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
 }
 
 func RegisterBlueprintRoutes(router api.RegisterRoute) {
@@ -34,6 +54,7 @@ func RegisterBlueprintRoutes(router api.RegisterRoute) {
 	router("GET", "/blueprints/new", newBlueprints)
 	router("GET", "/blueprints/search/", searchBlueprints)
 	router("GET", "/blueprints/search/{query}", searchBlueprints)
+	router("GET", "/blueprints/{mode}", modeBlueprints)
 
 	router("POST", "/blueprint", api.AuthHandler(postBlueprint, true))
 	router("GET", "/blueprint/{blueprint}", getBlueprint)
@@ -43,121 +64,146 @@ func RegisterBlueprintRoutes(router api.RegisterRoute) {
 	router("GET", "/blueprint/{blueprint}/revisions", getRevisions)
 	router("GET", "/blueprint/{blueprint}/revision/latest", getRevisionLatest)
 	router("GET", "/blueprint/{blueprint}/revision/{revision}", getRevisionIncremental)
+	router("GET", "/blueprint/{blueprint}/diff", getBlueprintDiff)
+
+	router("POST", "/blueprint/{blueprint}/fork", api.AuthHandler(postBlueprintFork, true))
+	router("GET", "/blueprint/{blueprint}/forks", getBlueprintForks)
+
+	router("GET", "/blueprint/{blueprint}/comments", getBlueprintComments)
+
+	router("POST", "/blueprint/{blueprint}/favorite", api.AuthHandler(postBlueprintFavorite, true))
+	router("DELETE", "/blueprint/{blueprint}/favorite", api.AuthHandler(deleteBlueprintFavorite, true))
 }
 
 type SearchBlueprintsResponse struct {
 	Blueprints []*BlueprintResponse `json:"blueprints"`
+	Facets     *Facets              `json:"facets,omitempty"`
+	Next       string               `json:"next,omitempty"`
+	Prev       string               `json:"prev,omitempty"`
+}
+
+// Facets count the whole result set of a search, not only the current page
+type Facets struct {
+	Tags         []FacetBucket `json:"tags"`
+	Authors      []FacetBucket `json:"authors"`
+	GameVersions []FacetBucket `json:"game-versions"`
+	Kinds        []FacetBucket `json:"kinds"`
+}
+
+type FacetBucket struct {
+	Id    uint   `json:"id,omitempty"`
+	Value string `json:"value"`
+	Count int    `json:"count"`
 }
 
 /*
 Search for blueprints
 */
 func searchBlueprints(r *http.Request) (interface{}, *utils.ErrorResponse) {
-	var (
-		offset, _ = strconv.Atoi(r.URL.Query().Get("offset"))
-		count, _  = strconv.Atoi(r.URL.Query().Get("count"))
-	)
-
-	if count == 0 {
-		count = 20
-	}
-	if count > 100 {
-		count = 100
-	}
-
 	query, ok := mux.Vars(r)["query"]
-	if !ok {
+	if !ok || db.SearchQuery(query) == "" {
 		return nil, &utils.Error_no_search_terms
 	}
 
-	blueprints := db.SearchBlueprints(query, offset, count)
-	reBlueprint := reBlueprintData(blueprints)
+	filter := parseBlueprintFilter(r)
 
-	return SearchBlueprintsResponse{
-		Blueprints: reBlueprint,
-	}, nil
+	response, e := listBlueprints(r, query, filter, "RELEVANCE", false)
+
+	if e != nil {
+		return nil, e
+	}
+
+	response.Facets = reFacetData(db.SearchFacets(query, filter))
+
+	return response, nil
 }
 
 /*
 Get popular blueprints
 */
 func popularBlueprints(r *http.Request) (interface{}, *utils.ErrorResponse) {
-	var (
-		offset, _ = strconv.Atoi(r.URL.Query().Get("offset"))
-		count, _  = strconv.Atoi(r.URL.Query().Get("count"))
-	)
-
-	if count == 0 {
-		count = 20
-	}
-	if count > 100 {
-		count = 100
-	}
+	return listBlueprints(r, "", db.BlueprintFilter{}, "POPULAR", false)
+}
 
-	blueprints := db.PopularBlueprints(offset, count)
-	reBlueprint := reBlueprintData(blueprints)
+/*
+Get top blueprints
+*/
+func topBlueprints(r *http.Request) (interface{}, *utils.ErrorResponse) {
+	return listBlueprints(r, "", db.BlueprintFilter{}, "TOP", false)
+}
 
-	return SearchBlueprintsResponse{
-		Blueprints: reBlueprint,
-	}, nil
+// Listing orders by their /blueprints/{mode} name, new, top and popular have
+// routes of their own
+var blueprintModes = map[string]string{
+	"best":          "BEST",
+	"trending":      "TRENDING",
+	"controversial": "CONTROVERSIAL",
 }
 
 /*
-Get popular blueprints
+Get blueprints ordered by a ranking mode
 */
-func topBlueprints(r *http.Request) (interface{}, *utils.ErrorResponse) {
-	var (
-		offset, _ = strconv.Atoi(r.URL.Query().Get("offset"))
-		count, _  = strconv.Atoi(r.URL.Query().Get("count"))
-	)
+func modeBlueprints(r *http.Request) (interface{}, *utils.ErrorResponse) {
+	order, ok := blueprintModes[mux.Vars(r)["mode"]]
 
-	if count == 0 {
-		count = 20
-	}
-	if count > 100 {
-		count = 100
+	if !ok {
+		return nil, &utils.Error_invalid_order
 	}
 
-	blueprints := db.TopBlueprints(offset, count)
-	reBlueprint := reBlueprintData(blueprints)
-
-	return SearchBlueprintsResponse{
-		Blueprints: reBlueprint,
-	}, nil
+	return listBlueprints(r, "", db.BlueprintFilter{}, order, false)
 }
 
 /*
-Get popular blueprints
+Get new blueprints
 */
 func newBlueprints(r *http.Request) (interface{}, *utils.ErrorResponse) {
-	var (
-		offset, _ = strconv.Atoi(r.URL.Query().Get("offset"))
-		count, _  = strconv.Atoi(r.URL.Query().Get("count"))
-	)
+	return listBlueprints(r, "", db.BlueprintFilter{}, "NEW", false)
+}
 
-	if count == 0 {
-		count = 20
+/*
+Get all blueprints (paged)
+*/
+func getBlueprints(r *http.Request) (interface{}, *utils.ErrorResponse) {
+	return listBlueprints(r, "", db.BlueprintFilter{}, "NEW", true)
+}
+
+func listBlueprints(r *http.Request, query string, filter db.BlueprintFilter, order string, ascending bool) (*SearchBlueprintsResponse, *utils.ErrorResponse) {
+	page, e := parsePage(r)
+
+	if e != nil {
+		return nil, e
 	}
-	if count > 100 {
-		count = 100
+
+	result, err := db.ListBlueprints(query, filter, order, ascending, page)
+
+	switch err {
+	case nil:
+	case db.ErrInvalidCursor:
+		return nil, &utils.Error_invalid_cursor
+	case db.ErrInvalidOrder:
+		return nil, &utils.Error_invalid_order
+	default:
+		return nil, &utils.Error_internal_error
 	}
 
-	blueprints := db.NewBlueprints(offset, count)
-	reBlueprint := reBlueprintData(blueprints)
+	response := &SearchBlueprintsResponse{
+		Blueprints: reSearchResultData(result.Blueprints),
+	}
 
-	return SearchBlueprintsResponse{
-		Blueprints: reBlueprint,
-	}, nil
-}
+	if result.Next != nil {
+		response.Next = result.Next.Encode()
+	}
 
-type GetBlueprintsResponse struct {
-	Blueprints []*BlueprintResponse `json:"blueprints"`
+	if result.Prev != nil {
+		response.Prev = result.Prev.Encode()
+	}
+
+	return response, nil
 }
 
-/*
-Get all blueprints (paged)
-*/
-func getBlueprints(r *http.Request) (interface{}, *utils.ErrorResponse) {
+// parsePage reads the cursor, offset and count query parameters. Offsets are
+// only used when no cursor is given.
+func parsePage(r *http.Request) (db.Page, *utils.ErrorResponse) {
 	var (
 		offset, _ = strconv.Atoi(r.URL.Query().Get("offset"))
 		count, _  = strconv.Atoi(r.URL.Query().Get("count"))
@@ -166,16 +212,27 @@ func getBlueprints(r *http.Request) (interface{}, *utils.ErrorResponse) {
 	if count == 0 {
 		count = 20
 	}
-	if count > 100 {
-		count = 100
+
+	if offset < 0 {
+		offset = 0
 	}
 
-	blueprints := db.GetAllBlueprints(offset, count)
-	reBlueprint := reBlueprintData(blueprints)
+	page := db.Page{
+		Offset: offset,
+		Limit:  utils.MinMax(1, count, 100),
+	}
 
-	return GetBlueprintsResponse{
-		Blueprints: reBlueprint,
-	}, nil
+	if token := r.URL.Query().Get("cursor"); token != "" {
+		cursor, err := db.DecodeCursor(token)
+
+		if err != nil {
+			return page, &utils.Error_invalid_cursor
+		}
+
+		page.Cursor = cursor
+	}
+
+	return page, nil
 }
 
 /*
@@ -208,8 +265,16 @@ func getBlueprint(r *http.Request) (interface{}, *utils.ErrorResponse) {
 	reTags := reTagData(tags)
 
 	var revId uint = 0
+	var thumbnail string
 	if rev := blueprint.GetLatestRevision(); rev != nil {
 		revId = rev.Revision
+		thumbnail = revisionRender(rev).Thumbnail
+	}
+
+	var favorited *bool
+	if authUser := db.GetAuthUser(r); authUser != nil {
+		isFavorite := db.IsFavorite(authUser.ID, blueprint.ID)
+		favorited = &isFavorite
 	}
 
 	return BlueprintResponse{
@@ -222,7 +287,14 @@ func getBlueprint(r *http.Request) (interface{}, *utils.ErrorResponse) {
 		Latest:      revId,
 		Revisions:   reRevision,
 		Tags:        reTags,
-		Thumbnail:   storage.PublicURL + "/" + storage.BlueprintRenderBucket + "/" + blueprint.GetThumbnail() + "-thumbnail.png",
+		Thumbnail:   thumbnail,
+
+		ForkedFrom:         blueprint.ForkedFromBlueprintID,
+		ForkedFromRevision: blueprint.ForkedFromRevisionID,
+		Forks:              blueprint.CountForks(),
+
+		Favorites: blueprint.CountFavorites(),
+		Favorited: favorited,
 	}, nil
 }
 
@@ -244,6 +316,9 @@ type PostBlueprintResponse struct {
 
 	Thumbnail string `json:"thumbnail"`
 	Render    string `json:"render"`
+
+	// URLs of all render variants by variant name
+	Renders map[string]string `json:"renders"`
 }
 
 /*
@@ -259,10 +334,6 @@ func postBlueprint(u *db.User, r *http.Request) (interface{}, *utils.ErrorRespon
 
 	sha265 := utils.SHA265(request.BlueprintString)
 
-	if db.FindRevisionByChecksum(sha265) != nil {
-		return nil, &utils.Error_blueprint_string_already_exists
-	}
-
 	blueprint := &db.Blueprint{
 		UserID:       u.ID,
 		Name:         request.Name,
@@ -273,6 +344,7 @@ func postBlueprint(u *db.User, r *http.Request) (interface{}, *utils.ErrorRespon
 	blueprint.Save()
 
 	bpVersion, _ := strconv.Atoi(request.BlueprintString[0:1])
+	container, _ := bp.Decode(request.BlueprintString)
 
 	revision := &db.Revision{
 		BlueprintID:       blueprint.ID,
@@ -282,10 +354,7 @@ func postBlueprint(u *db.User, r *http.Request) (interface{}, *utils.ErrorRespon
 		BlueprintChecksum: sha265,
 	}
 
-	revision.Save()
-
-	storage.SaveRevision(revision.ID, request.BlueprintString)
-	go storage.RenderAndSaveAndUpdateBlueprint(request.BlueprintString, revision)
+	saveRevision(revision, container, request.BlueprintString)
 
 	for _, tag := range request.Tags {
 
@@ -307,14 +376,15 @@ func postBlueprint(u *db.User, r *http.Request) (interface{}, *utils.ErrorRespon
 		bt.Save()
 	}
 
-	baseRenderStorageURL := storage.PublicURL + "/" + storage.BlueprintRenderBucket + "/" + revision.BlueprintChecksum
+	revisionsChanged(blueprint.ID)
 
 	return PostBlueprintResponse{
 		BlueprintId: blueprint.ID,
 		RevisionId:  revision.ID,
 		Revision:    revision.Revision,
-		Thumbnail:   baseRenderStorageURL + "-thumbnail.png",
-		Render:      baseRenderStorageURL + ".png",
+		Thumbnail:   render.URL(revision.BlueprintChecksum, "thumbnail"),
+		Render:      render.URL(revision.BlueprintChecksum, "render"),
+		Renders:     render.URLs(revision.BlueprintChecksum),
 	}, nil
 }
 
@@ -345,6 +415,8 @@ func updateBlueprint(u *db.User, r *http.Request) (interface{}, *utils.ErrorResp
 		return nil, &utils.Error_no_access
 	}
 
+	indexed := blueprint.Name != request.Name || blueprint.Description != request.Description || !sameTags(blueprint.GetTags(), request.Tags)
+
 	for _, t := range blueprint.GetTags() {
 		bt := db.BlueprintTag{
 			BlueprintId: blueprint.ID,
@@ -374,6 +446,10 @@ func updateBlueprint(u *db.User, r *http.Request) (interface{}, *utils.ErrorResp
 
 	blueprint.Save()
 
+	if indexed {
+		db.UpdateBlueprintSearch(blueprint.ID)
+	}
+
 	return nil, nil
 }
 
@@ -391,11 +467,214 @@ func deleteBlueprint(u *db.User, r *http.Request) (interface{}, *utils.ErrorResp
 		return nil, &utils.Error_no_access
 	}
 
-	blueprint.Delete()
+	removeBlueprint(blueprint)
+
+	return nil, nil
+}
+
+type PostBlueprintForkRequest struct {
+	// Incremental version to fork, the latest one if zero
+	Revision uint `json:"revision"`
+
+	// Name and description of the fork, the ones of the original if empty
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+/*
+Fork a revision of another user's blueprint into a new blueprint
+*/
+func postBlueprintFork(u *db.User, r *http.Request) (interface{}, *utils.ErrorResponse) {
+	var request PostBlueprintForkRequest
+	e := utils.ValidateRequestBody(r, &request)
+
+	if e != nil {
+		return nil, e
+	}
+
+	if request.Name != "" && len(request.Name) < 5 {
+		return nil, &utils.Error_invalid_request_data
+	}
+
+	blueprint, e := parseBlueprint(r)
+
+	if e != nil {
+		return nil, e
+	}
+
+	fork, revision, e := forkBlueprint(u, blueprint, request.Revision, request.Name, request.Description)
+
+	if e != nil {
+		return nil, e
+	}
+
+	return PostBlueprintResponse{
+		BlueprintId: fork.ID,
+		RevisionId:  revision.ID,
+		Revision:    revision.Revision,
+		Thumbnail:   render.URL(revision.BlueprintChecksum, "thumbnail"),
+		Render:      render.URL(revision.BlueprintChecksum, "render"),
+		Renders:     render.URLs(revision.BlueprintChecksum),
+	}, nil
+}
+
+/*
+Get the forks of a blueprint (paged)
+*/
+func getBlueprintForks(r *http.Request) (interface{}, *utils.ErrorResponse) {
+	blueprint, e := parseBlueprint(r)
+
+	if e != nil {
+		return nil, e
+	}
+
+	return listBlueprints(r, "", db.BlueprintFilter{ForkedFrom: blueprint.ID}, "NEW", false)
+}
+
+type GetBlueprintCommentsResponse struct {
+	Comments []*Comment `json:"comments"`
+}
+
+/*
+Get the comments on all revisions of a blueprint, labelled with their revision
+*/
+func getBlueprintComments(r *http.Request) (interface{}, *utils.ErrorResponse) {
+	blueprint, e := parseBlueprint(r)
+
+	if e != nil {
+		return nil, e
+	}
+
+	filter, e := parseCommentFilter(r)
+
+	if e != nil {
+		return nil, e
+	}
+
+	revisions := make(map[uint]uint)
+
+	for _, revision := range blueprint.GetRevisions() {
+		revisions[revision.ID] = revision.Revision
+	}
+
+	comments := reCommentTree(blueprint.GetComments(filter))
+	labelComments(comments, revisions)
+
+	return GetBlueprintCommentsResponse{
+		Comments: comments,
+	}, nil
+}
+
+/*
+Add a blueprint to the favorites of the user
+*/
+func postBlueprintFavorite(u *db.User, r *http.Request) (interface{}, *utils.ErrorResponse) {
+	blueprint, e := parseBlueprint(r)
+
+	if e != nil {
+		return nil, e
+	}
+
+	favorite := db.FindFavorite(u.ID, blueprint.ID)
+
+	favorite.UserID = u.ID
+	favorite.BlueprintID = blueprint.ID
+	favorite.DeletedAt = nil
+	favorite.Save()
+
+	return nil, nil
+}
+
+/*
+Remove a blueprint from the favorites of the user
+*/
+func deleteBlueprintFavorite(u *db.User, r *http.Request) (interface{}, *utils.ErrorResponse) {
+	blueprint, e := parseBlueprint(r)
+
+	if e != nil {
+		return nil, e
+	}
+
+	favorite := db.FindFavorite(u.ID, blueprint.ID)
+
+	if favorite.ID == 0 || favorite.DeletedAt != nil {
+		return nil, &utils.Error_favorite_not_found
+	}
+
+	favorite.Delete()
 
 	return nil, nil
 }
 
+// forkBlueprint copies a revision of the source into a new blueprint owned by
+// the user, with the tags of the source. Revision zero is the latest one.
+func forkBlueprint(u *db.User, source *db.Blueprint, revisionId uint, name string, description string) (*db.Blueprint, *db.Revision, *utils.ErrorResponse) {
+	if source.UserID == u.ID {
+		return nil, nil, &utils.Error_blueprint_own_fork
+	}
+
+	var sourceRevision *db.Revision
+
+	if revisionId == 0 {
+		sourceRevision = source.GetLatestRevision()
+	} else {
+		sourceRevision = source.GetRevision(revisionId)
+	}
+
+	if sourceRevision == nil || sourceRevision.DeletedAt != nil {
+		return nil, nil, &utils.Error_revision_not_found
+	}
+
+	blueprintString := storage.GetRevision(sourceRevision)
+
+	if blueprintString == nil {
+		return nil, nil, &utils.Error_internal_error
+	}
+
+	container, err := bp.Decode(*blueprintString)
+
+	if err != nil {
+		return nil, nil, &utils.Error_internal_error
+	}
+
+	if name == "" {
+		name = source.Name
+	}
+
+	if description == "" {
+		description = source.Description
+	}
+
+	fork := &db.Blueprint{
+		UserID:                u.ID,
+		Name:                  name,
+		Description:           description,
+		LastRevision:          1,
+		ForkedFromBlueprintID: &source.ID,
+		ForkedFromRevisionID:  &sourceRevision.ID,
+	}
+
+	fork.Save()
+
+	revision := &db.Revision{
+		BlueprintID:       fork.ID,
+		Revision:          fork.LastRevision,
+		Changes:           "",
+		BlueprintVersion:  sourceRevision.BlueprintVersion,
+		BlueprintChecksum: sourceRevision.BlueprintChecksum,
+	}
+
+	saveRevision(revision, container, *blueprintString)
+
+	for _, tag := range source.GetTags() {
+		fork.AddTag(tag.ID)
+	}
+
+	revisionsChanged(fork.ID)
+
+	return fork, revision, nil
+}
+
 type GetRevisionsResponse struct {
 	Revisions []*Revision `json:"revisions"`
 }
@@ -467,6 +746,183 @@ func getRevisionIncremental(r *http.Request) (interface{}, *utils.ErrorResponse)
 	return revisionToJSON(authUser, revision, getComments)
 }
 
+type BlueprintDiffResponse struct {
+	From  uint `json:"from"`
+	To    uint `json:"to"`
+	Child *int `json:"child,omitempty"`
+
+	*bp.Diff
+}
+
+/*
+Get the entity level changes between two revisions of a blueprint, books are compared by child
+*/
+func getBlueprintDiff(r *http.Request) (interface{}, *utils.ErrorResponse) {
+	blueprint, e := parseBlueprint(r)
+
+	if e != nil {
+		return nil, e
+	}
+
+	from, to, child, e := parseDiffQuery(r)
+
+	if e != nil {
+		return nil, e
+	}
+
+	return blueprintDiff(blueprint, from, to, child)
+}
+
+// parseDiffQuery reads the from and to revisions and the optional child
+func parseDiffQuery(r *http.Request) (uint, uint, *int, *utils.ErrorResponse) {
+	query := r.URL.Query()
+
+	from, err := strconv.ParseUint(query.Get("from"), 10, 32)
+
+	if err != nil {
+		return 0, 0, nil, &utils.Error_invalid_request_data
+	}
+
+	to, err := strconv.ParseUint(query.Get("to"), 10, 32)
+
+	if err != nil {
+		return 0, 0, nil, &utils.Error_invalid_request_data
+	}
+
+	if query.Get("child") == "" {
+		return uint(from), uint(to), nil, nil
+	}
+
+	child, err := strconv.Atoi(query.Get("child"))
+
+	if err != nil {
+		return 0, 0, nil, &utils.Error_invalid_request_data
+	}
+
+	return uint(from), uint(to), &child, nil
+}
+
+// diffPair holds the compared revisions and their blueprints, blueprints of
+// missing book entries are nil
+type diffPair struct {
+	fromRevision  *db.Revision
+	toRevision    *db.Revision
+	fromBlueprint *bp.Blueprint
+	toBlueprint   *bp.Blueprint
+}
+
+// blueprintDiff compares two revisions of the blueprint, with a child the
+// entries of two books at that index are compared instead
+func blueprintDiff(blueprint *db.Blueprint, from uint, to uint, child *int) (*BlueprintDiffResponse, *utils.ErrorResponse) {
+	pair, e := findDiffPair(blueprint, from, to, child)
+
+	if e != nil {
+		return nil, e
+	}
+
+	return &BlueprintDiffResponse{
+		From:  from,
+		To:    to,
+		Child: child,
+		Diff:  bp.DiffBlueprints(pair.fromBlueprint, pair.toBlueprint),
+	}, nil
+}
+
+func findDiffPair(blueprint *db.Blueprint, from uint, to uint, child *int) (*diffPair, *utils.ErrorResponse) {
+	var (
+		pair diffPair
+		e    *utils.ErrorResponse
+	)
+
+	pair.fromRevision, pair.fromBlueprint, e = diffBlueprint(blueprint, from, child)
+
+	if e != nil {
+		return nil, e
+	}
+
+	pair.toRevision, pair.toBlueprint, e = diffBlueprint(blueprint, to, child)
+
+	if e != nil {
+		return nil, e
+	}
+
+	// Entries may be added or removed between books but not be missing in both
+	if pair.fromBlueprint == nil && pair.toBlueprint == nil {
+		return nil, &utils.Error_revision_child_not_found
+	}
+
+	return &pair, nil
+}
+
+// diffBlueprint decodes the blueprint of a revision, or of the book entry at
+// the child index which is nil if there is no such entry
+func diffBlueprint(blueprint *db.Blueprint, revisionId uint, child *int) (*db.Revision, *bp.Blueprint, *utils.ErrorResponse) {
+	revision := blueprint.GetRevision(revisionId)
+
+	if revision == nil || revision.DeletedAt != nil {
+		return nil, nil, &utils.Error_revision_not_found
+	}
+
+	if child == nil && revision.Kind != bp.KindBlueprint {
+		return nil, nil, &utils.Error_revision_not_blueprint
+	}
+
+	if child != nil && revision.Kind != bp.KindBlueprintBook {
+		return nil, nil, &utils.Error_revision_not_book
+	}
+
+	container := storage.GetRevisionBlueprint(revision)
+
+	if container == nil || (child == nil && container.Blueprint == nil) || (child != nil && container.BlueprintBook == nil) {
+		return nil, nil, &utils.Error_internal_error
+	}
+
+	if child == nil {
+		return revision, container.Blueprint, nil
+	}
+
+	entry := container.BlueprintBook.Child(*child)
+
+	if entry == nil {
+		return revision, nil, nil
+	}
+
+	if entry.Blueprint == nil {
+		return nil, nil, &utils.Error_revision_not_blueprint
+	}
+
+	return revision, entry.Blueprint, nil
+}
+
+// sameTags reports whether the tags have the given names in any order
+func sameTags(tags []*db.Tag, names []string) bool {
+	remaining := make(map[string]int, len(names))
+
+	for _, name := range names {
+		remaining[name]++
+	}
+
+	for _, tag := range tags {
+		if remaining[tag.Name] == 0 {
+			return false
+		}
+
+		remaining[tag.Name]--
+	}
+
+	return len(tags) == len(names)
+}
+
+// removeBlueprint deletes the blueprint with all its revisions, releasing
+// their strings
+func removeBlueprint(blueprint *db.Blueprint) {
+	for _, revision := range blueprint.GetRevisions() {
+		removeRevision(revision)
+	}
+
+	blueprint.Delete()
+}
+
 func parseBlueprint(r *http.Request) (*db.Blueprint, *utils.ErrorResponse) {
 	blueprintId, err := strconv.ParseUint(mux.Vars(r)["blueprint"], 10, 32)
 
@@ -489,16 +945,23 @@ func findBlueprintById(blueprintId uint) (*db.Blueprint, *utils.ErrorResponse) {
 
 func reBlueprintData(blueprints []*db.Blueprint) []*BlueprintResponse {
 	reBlueprint := make([]*BlueprintResponse, len(blueprints))
+	thumbnails := blueprintThumbnails(blueprints)
+
+	ids := make([]uint, len(blueprints))
+
+	for i, blueprint := range blueprints {
+		ids[i] = blueprint.ID
+	}
+
+	forks := db.CountBlueprintForks(ids)
+	favorites := db.CountBlueprintFavorites(ids)
 
 	for i, blueprint := range blueprints {
 		var revId uint = 0
-		rev := blueprint.GetLatestRevision()
 
 		tags := blueprint.GetTags()
 		reTags := reTagData(tags)
 
-		baseRenderStorageURL := storage.PublicURL + "/" + storage.BlueprintRenderBucket + "/" + rev.BlueprintChecksum
-
 		reBlueprint[i] = &BlueprintResponse{
 			Id:          blueprint.ID,
 			UserId:      blueprint.UserID,
@@ -508,9 +971,163 @@ func reBlueprintData(blueprints []*db.Blueprint) []*BlueprintResponse {
 			UpdatedAt:   blueprint.UpdatedAt,
 			Latest:      revId,
 			Tags:        reTags,
-			Thumbnail:   baseRenderStorageURL + "-thumbnail.png",
+			Thumbnail:   thumbnails[blueprint.ID],
+
+			ForkedFrom:         blueprint.ForkedFromBlueprintID,
+			ForkedFromRevision: blueprint.ForkedFromRevisionID,
+			Forks:              forks[blueprint.ID],
+
+			Favorites: favorites[blueprint.ID],
+		}
+	}
+
+	return reBlueprint
+}
+
+// blueprintThumbnail is the thumbnail of the latest revision, empty until it
+// is rendered
+func blueprintThumbnail(blueprint *db.Blueprint) string {
+	if rev := blueprint.GetLatestRevision(); rev != nil {
+		return revisionRender(rev).Thumbnail
+	}
+	return ""
+}
+
+// blueprintThumbnails of several blueprints by blueprint id
+func blueprintThumbnails(blueprints []*db.Blueprint) map[uint]string {
+	latest := make([]*db.Revision, 0, len(blueprints))
+
+	for _, blueprint := range blueprints {
+		if blueprint == nil {
+			continue
+		}
+
+		if rev := blueprint.GetLatestRevision(); rev != nil {
+			latest = append(latest, rev)
+		}
+	}
+
+	states := revisionRenders(latest)
+	thumbnails := make(map[uint]string, len(latest))
+
+	for _, rev := range latest {
+		thumbnails[rev.BlueprintID] = states[rev.ID].Thumbnail
+	}
+
+	return thumbnails
+}
+
+func reSearchResultData(results []*db.BlueprintSearchResult) []*BlueprintResponse {
+	blueprints := make([]*db.Blueprint, len(results))
+
+	for i, result := range results {
+		blueprints[i] = &result.Blueprint
+	}
+
+	reBlueprint := reBlueprintData(blueprints)
+
+	for i, result := range results {
+		if result.HighlightName == "" && result.HighlightDescription == "" {
+			continue
+		}
+
+		reBlueprint[i].Highlight = &Highlight{
+			Name:        result.HighlightName,
+			Description: result.HighlightDescription,
 		}
 	}
 
 	return reBlueprint
 }
+
+func parseBlueprintFilter(r *http.Request) db.BlueprintFilter {
+	var (
+		values       = r.URL.Query()
+		author, _    = strconv.ParseUint(values.Get("author"), 10, 32)
+		maxWidth, _  = strconv.Atoi(values.Get("max-width"))
+		maxHeight, _ = strconv.Atoi(values.Get("max-height"))
+	)
+
+	return db.BlueprintFilter{
+		HasEntities:     values["entity"],
+		WithoutEntities: values["without-entity"],
+		MaxWidth:        maxWidth,
+		MaxHeight:       maxHeight,
+		GameVersion:     values.Get("game-version"),
+		Tags:            values["tag"],
+		Author:          uint(author),
+		Kinds:           values["kind"],
+	}
+}
+
+// parseCommentFilter reads the revision number, author and creation date
+// (RFC 3339) the comments are filtered by
+func parseCommentFilter(r *http.Request) (db.CommentFilter, *utils.ErrorResponse) {
+	var (
+		filter db.CommentFilter
+		values = r.URL.Query()
+	)
+
+	if value := values.Get("revision"); value != "" {
+		revision, err := strconv.ParseUint(value, 10, 32)
+
+		if err != nil {
+			return filter, &utils.Error_invalid_request_data
+		}
+
+		filter.Revision = uint(revision)
+	}
+
+	if value := values.Get("author"); value != "" {
+		author, err := strconv.ParseUint(value, 10, 32)
+
+		if err != nil {
+			return filter, &utils.Error_invalid_request_data
+		}
+
+		filter.Author = uint(author)
+	}
+
+	if value := values.Get("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+
+		if err != nil {
+			return filter, &utils.Error_invalid_request_data
+		}
+
+		filter.Since = &since
+	}
+
+	return filter, nil
+}
+
+// labelComments sets the revision number of the comments and their replies
+func labelComments(comments []*Comment, revisions map[uint]uint) {
+	for _, comment := range comments {
+		comment.Revision = revisions[comment.RevisionId]
+		labelComments(comment.Replies, revisions)
+	}
+}
+
+func reFacetData(facets *db.Facets) *Facets {
+	return &Facets{
+		Tags:         reFacetBuckets(facets.Tags),
+		Authors:      reFacetBuckets(facets.Authors),
+		GameVersions: reFacetBuckets(facets.GameVersions),
+		Kinds:        reFacetBuckets(facets.Kinds),
+	}
+}
+
+func reFacetBuckets(buckets []db.FacetBucket) []FacetBucket {
+	result := make([]FacetBucket, len(buckets))
+
+	for i, bucket := range buckets {
+		result[i] = FacetBucket{
+			Id:    bucket.ID,
+			Value: bucket.Value,
+			Count: bucket.Count,
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,372 @@
+package nodes
+
+import (
+	"net/http"
+
+	"time"
+
+	"strconv"
+
+	"github.com/BlooperDB/API/api"
+	bp "github.com/BlooperDB/API/blueprint"
+	"github.com/BlooperDB/API/db"
+	"github.com/BlooperDB/API/storage"
+	"github.com/BlooperDB/API/utils"
+	"github.com/gorilla/mux"
+)
+
+type CollectionResponse struct {
+	Id             uint                 `json:"id"`
+	UserId         uint                 `json:"user"`
+	Title          string               `json:"title"`
+	Description    string               `json:"description"`
+	Visibility     string               `json:"visibility"`
+	BlueprintCount int                  `json:"blueprint-count"`
+	Blueprints     []*BlueprintResponse `json:"blueprints,omitempty"`
+	CreatedAt      time.Time            `json:"created-at"`
+	UpdatedAt      time.Time            `json:"updated-at"`
+}
+
+func RegisterCollectionRoutes(router api.RegisterRoute) {
+	router("POST", "/collection", api.AuthHandler(postCollection, true))
+	router("GET", "/collection/{collection}", getCollection)
+	router("PUT", "/collection/{collection}", api.AuthHandler(updateCollection, true))
+	router("DELETE", "/collection/{collection}", api.AuthHandler(deleteCollection, true))
+
+	router("PUT", "/collection/{collection}/blueprints", api.AuthHandler(putCollectionBlueprints, true))
+	router("GET", "/collection/{collection}/export", exportCollection)
+
+	router("GET", "/user/self/collections", api.AuthHandler(getUserSelfCollections, false))
+	router("GET", "/user/{user}/collections", getUserCollections)
+}
+
+/*
+Get a collection with its blueprints in order
+*/
+func getCollection(r *http.Request) (interface{}, *utils.ErrorResponse) {
+	collection, e := parseCollection(r, db.GetAuthUser(r))
+
+	if e != nil {
+		return nil, e
+	}
+
+	response := reCollection(collection)
+	response.Blueprints = reBlueprintData(collection.GetBlueprints())
+
+	return response, nil
+}
+
+type PostCollectionRequest struct {
+	Title       string `json:"title" validate:"min=3"`
+	Description string `json:"description"`
+	Visibility  string `json:"visibility"`
+	Blueprints  []uint `json:"blueprints"`
+}
+
+type PostCollectionResponse struct {
+	CollectionId uint `json:"collection-id"`
+}
+
+/*
+Create a collection
+*/
+func postCollection(u *db.User, r *http.Request) (interface{}, *utils.ErrorResponse) {
+	var request PostCollectionRequest
+	e := utils.ValidateRequestBody(r, &request)
+
+	if e != nil {
+		return nil, e
+	}
+
+	visibility, e := parseVisibility(request.Visibility)
+
+	if e != nil {
+		return nil, e
+	}
+
+	if e := validateCollectionBlueprints(request.Blueprints); e != nil {
+		return nil, e
+	}
+
+	collection := &db.Collection{
+		UserID:      u.ID,
+		Title:       request.Title,
+		Description: request.Description,
+		Visibility:  visibility,
+	}
+
+	collection.Save()
+	collection.SetBlueprints(request.Blueprints)
+
+	return PostCollectionResponse{
+		CollectionId: collection.ID,
+	}, nil
+}
+
+type PutCollectionRequest struct {
+	Title       string `json:"title" validate:"min=3"`
+	Description string `json:"description"`
+	Visibility  string `json:"visibility"`
+}
+
+/*
+Update the title, description and visibility of a collection
+*/
+func updateCollection(u *db.User, r *http.Request) (interface{}, *utils.ErrorResponse) {
+	var request PutCollectionRequest
+	e := utils.ValidateRequestBody(r, &request)
+
+	if e != nil {
+		return nil, e
+	}
+
+	collection, e := parseOwnCollection(r, u)
+
+	if e != nil {
+		return nil, e
+	}
+
+	visibility, e := parseVisibility(request.Visibility)
+
+	if e != nil {
+		return nil, e
+	}
+
+	collection.Title = request.Title
+	collection.Description = request.Description
+	collection.Visibility = visibility
+
+	collection.Save()
+
+	return nil, nil
+}
+
+/*
+Delete a collection, the blueprints in it are kept
+*/
+func deleteCollection(u *db.User, r *http.Request) (interface{}, *utils.ErrorResponse) {
+	collection, e := parseOwnCollection(r, u)
+
+	if e != nil {
+		return nil, e
+	}
+
+	collection.Delete()
+
+	return nil, nil
+}
+
+type PutCollectionBlueprintsRequest struct {
+	Blueprints []uint `json:"blueprints"`
+}
+
+/*
+Replace the blueprints of a collection, in the given order
+*/
+func putCollectionBlueprints(u *db.User, r *http.Request) (interface{}, *utils.ErrorResponse) {
+	var request PutCollectionBlueprintsRequest
+	e := utils.ValidateRequestBody(r, &request)
+
+	if e != nil {
+		return nil, e
+	}
+
+	collection, e := parseOwnCollection(r, u)
+
+	if e != nil {
+		return nil, e
+	}
+
+	if e := validateCollectionBlueprints(request.Blueprints); e != nil {
+		return nil, e
+	}
+
+	collection.SetBlueprints(request.Blueprints)
+
+	return nil, nil
+}
+
+type ExportCollectionResponse struct {
+	BlueprintString string `json:"blueprint-string"`
+}
+
+/*
+Export a collection as a blueprint book of the latest revision of each blueprint
+*/
+func exportCollection(r *http.Request) (interface{}, *utils.ErrorResponse) {
+	collection, e := parseCollection(r, db.GetAuthUser(r))
+
+	if e != nil {
+		return nil, e
+	}
+
+	blueprintString, e := collectionBook(collection)
+
+	if e != nil {
+		return nil, e
+	}
+
+	return ExportCollectionResponse{
+		BlueprintString: blueprintString,
+	}, nil
+}
+
+type CollectionsResponse struct {
+	Collections []*CollectionResponse `json:"collections"`
+}
+
+/*
+Get the public collections of a user
+*/
+func getUserCollections(r *http.Request) (interface{}, *utils.ErrorResponse) {
+	userId, err := strconv.ParseUint(mux.Vars(r)["user"], 10, 32)
+	if err != nil {
+		return nil, &utils.Error_user_not_found
+	}
+
+	user := db.GetUserById(uint(userId))
+	if user == nil {
+		return nil, &utils.Error_user_not_found
+	}
+
+	return CollectionsResponse{
+		Collections: reCollectionData(db.GetUserCollections(user.ID, false)),
+	}, nil
+}
+
+/*
+Get all collections of the authenticated user
+*/
+func getUserSelfCollections(u *db.User, r *http.Request) (interface{}, *utils.ErrorResponse) {
+	return CollectionsResponse{
+		Collections: reCollectionData(db.GetUserCollections(u.ID, true)),
+	}, nil
+}
+
+// collectionBook encodes a book holding the latest revision of every blueprint
+// in the collection, named after the collection
+func collectionBook(collection *db.Collection) (string, *utils.ErrorResponse) {
+	var containers []*bp.Container
+
+	for _, blueprint := range collection.GetBlueprints() {
+		revision := blueprint.GetLatestRevision()
+
+		if revision == nil || revision.DeletedAt != nil {
+			continue
+		}
+
+		container := storage.GetRevisionBlueprint(revision)
+
+		if container == nil {
+			return "", &utils.Error_internal_error
+		}
+
+		containers = append(containers, container)
+	}
+
+	if len(containers) == 0 {
+		return "", &utils.Error_collection_empty
+	}
+
+	blueprintString, err := bp.Encode(bp.NewBook(collection.Title, collection.Description, containers))
+
+	if err != nil {
+		return "", &utils.Error_internal_error
+	}
+
+	return blueprintString, nil
+}
+
+// validateCollectionBlueprints checks that all blueprints exist and none is
+// listed twice
+func validateCollectionBlueprints(blueprints []uint) *utils.ErrorResponse {
+	seen := make(map[uint]bool, len(blueprints))
+
+	for _, id := range blueprints {
+		if seen[id] {
+			return &utils.Error_invalid_request_data
+		}
+
+		seen[id] = true
+
+		if db.GetBlueprintById(id) == nil {
+			return &utils.Error_blueprint_not_found
+		}
+	}
+
+	return nil
+}
+
+// parseVisibility defaults to public
+func parseVisibility(visibility string) (string, *utils.ErrorResponse) {
+	if visibility == "" {
+		return db.CollectionPublic, nil
+	}
+
+	for _, v := range db.CollectionVisibilities {
+		if v == visibility {
+			return visibility, nil
+		}
+	}
+
+	return "", &utils.Error_invalid_request_data
+}
+
+// parseCollection finds the collection of the request, private collections of
+// other users are not found
+func parseCollection(r *http.Request, user *db.User) (*db.Collection, *utils.ErrorResponse) {
+	collectionId, err := strconv.ParseUint(mux.Vars(r)["collection"], 10, 32)
+
+	if err != nil {
+		return nil, &utils.Error_collection_not_found
+	}
+
+	return findCollectionById(uint(collectionId), user)
+}
+
+func parseOwnCollection(r *http.Request, user *db.User) (*db.Collection, *utils.ErrorResponse) {
+	collection, e := parseCollection(r, user)
+
+	if e != nil {
+		return nil, e
+	}
+
+	if collection.UserID != user.ID {
+		return nil, &utils.Error_no_access
+	}
+
+	return collection, nil
+}
+
+func findCollectionById(collectionId uint, user *db.User) (*db.Collection, *utils.ErrorResponse) {
+	collection := db.GetCollectionById(collectionId)
+
+	if collection == nil || !collection.VisibleTo(user) {
+		return nil, &utils.Error_collection_not_found
+	}
+
+	return collection, nil
+}
+
+func reCollection(collection *db.Collection) *CollectionResponse {
+	return &CollectionResponse{
+		Id:             collection.ID,
+		UserId:         collection.UserID,
+		Title:          collection.Title,
+		Description:    collection.Description,
+		Visibility:     collection.Visibility,
+		BlueprintCount: collection.CountBlueprints(),
+		CreatedAt:      collection.CreatedAt,
+		UpdatedAt:      collection.UpdatedAt,
+	}
+}
+
+func reCollectionData(collections []*db.Collection) []*CollectionResponse {
+	reCollections := make([]*CollectionResponse, len(collections))
+
+	for i, collection := range collections {
+		reCollections[i] = reCollection(collection)
+	}
+
+	return reCollections
+}
@@ -14,12 +14,17 @@ import (
 )
 
 type Comment struct {
-	Id         uint      `json:"id"`
-	UserId     uint      `json:"user"`
-	CreatedAt  time.Time `json:"created-at"`
-	UpdatedAt  time.Time `json:"updated-at"`
-	Message    string    `json:"message"`
-	RevisionId uint      `json:"revision-id"`
+	Id         uint       `json:"id"`
+	UserId     uint       `json:"user"`
+	CreatedAt  time.Time  `json:"created-at"`
+	UpdatedAt  time.Time  `json:"updated-at"`
+	Message    string     `json:"message"`
+	RevisionId uint       `json:"revision-id"`
+	Revision   uint       `json:"revision,omitempty"`
+	ParentId   *uint      `json:"parent-id,omitempty"`
+	Depth      int        `json:"depth"`
+	ReplyCount int        `json:"reply-count"`
+	Replies    []*Comment `json:"replies,omitempty"`
 }
 
 func RegisterCommentRoutes(router api.RegisterRoute) {
@@ -46,12 +51,18 @@ func getComment(r *http.Request) (interface{}, *utils.ErrorResponse) {
 		UpdatedAt:  comment.UpdatedAt,
 		Message:    comment.Message,
 		RevisionId: comment.RevisionID,
+		ParentId:   comment.ParentID,
+		Depth:      comment.Depth,
+		ReplyCount: comment.CountReplies(),
 	}, nil
 }
 
 type PostCommentRequest struct {
 	Message    string `json:"message" validate:"nonzero"`
-	RevisionId uint   `json:"revision-id" validate:"nonzero"`
+	RevisionId uint   `json:"revision-id"`
+
+	// Comment to reply to, the revision may be omitted for replies
+	ParentId uint `json:"parent-id"`
 }
 
 type PostCommentResponse struct {
@@ -69,13 +80,11 @@ func postComment(u *db.User, r *http.Request) (interface{}, *utils.ErrorResponse
 		return nil, e
 	}
 
-	comment := &db.Comment{
-		RevisionID: request.RevisionId,
-		UserID:     u.ID,
-		Message:    request.Message,
-	}
+	comment, e := newComment(u, request.RevisionId, request.ParentId, request.Message)
 
-	comment.Save()
+	if e != nil {
+		return nil, e
+	}
 
 	return PostCommentResponse{
 		CommentId: comment.ID,
@@ -132,6 +141,44 @@ func deleteComment(u *db.User, r *http.Request) (interface{}, *utils.ErrorRespon
 	return nil, nil
 }
 
+// newComment saves a comment on the revision or a reply to the parent, replies
+// are nested at most db.MaxCommentDepth levels deep
+func newComment(u *db.User, revisionId uint, parentId uint, message string) (*db.Comment, *utils.ErrorResponse) {
+	comment := &db.Comment{
+		RevisionID: revisionId,
+		UserID:     u.ID,
+		Message:    message,
+	}
+
+	if parentId != 0 {
+		parent := db.GetCommentById(parentId)
+
+		if parent == nil {
+			return nil, &utils.Error_comment_not_found
+		}
+
+		if revisionId != 0 && parent.RevisionID != revisionId {
+			return nil, &utils.Error_invalid_request_data
+		}
+
+		if parent.Depth >= db.MaxCommentDepth {
+			return nil, &utils.Error_comment_too_deep
+		}
+
+		comment.RevisionID = parent.RevisionID
+		comment.ParentID = &parent.ID
+		comment.Depth = parent.Depth + 1
+	}
+
+	if revision := db.GetRevisionById(comment.RevisionID); revision == nil {
+		return nil, &utils.Error_revision_not_found
+	}
+
+	comment.Save()
+
+	return comment, nil
+}
+
 func parseComment(r *http.Request) (*db.Comment, *utils.ErrorResponse) {
 	commentId, err := strconv.ParseUint(mux.Vars(r)["comment"], 10, 32)
 
@@ -150,16 +197,53 @@ func parseComment(r *http.Request) (*db.Comment, *utils.ErrorResponse) {
 
 func reCommentData(comments []*db.Comment) []*Comment {
 	reComment := make([]*Comment, len(comments))
+	ids := make([]uint, len(comments))
+
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+
+	replies := db.CountCommentReplies(ids)
 
 	for i, comment := range comments {
 		reComment[i] = &Comment{
-			Id:        comment.ID,
-			UserId:    comment.UserID,
-			CreatedAt: comment.CreatedAt,
-			UpdatedAt: comment.UpdatedAt,
-			Message:   comment.Message,
+			Id:         comment.ID,
+			UserId:     comment.UserID,
+			CreatedAt:  comment.CreatedAt,
+			UpdatedAt:  comment.UpdatedAt,
+			Message:    comment.Message,
+			RevisionId: comment.RevisionID,
+			ParentId:   comment.ParentID,
+			Depth:      comment.Depth,
+			ReplyCount: replies[comment.ID],
 		}
 	}
 
 	return reComment
 }
+
+// reCommentTree nests the comments below the comments they reply to, keeping
+// their order. Replies to comments missing from the list are top level.
+func reCommentTree(comments []*db.Comment) []*Comment {
+	reComment := reCommentData(comments)
+	byId := make(map[uint]*Comment, len(reComment))
+
+	for _, comment := range reComment {
+		byId[comment.Id] = comment
+	}
+
+	var tree []*Comment
+
+	for _, comment := range reComment {
+		if comment.ParentId != nil {
+			if parent, ok := byId[*comment.ParentId]; ok {
+				parent.Replies = append(parent.Replies, comment)
+				continue
+			}
+		}
+
+		tree = append(tree, comment)
+	}
+
+	return tree
+}
@@ -2,9 +2,13 @@ package nodes
 
 import (
 	"errors"
+	"sort"
 	"strconv"
+	"time"
 
+	bp "github.com/BlooperDB/API/blueprint"
 	"github.com/BlooperDB/API/db"
+	"github.com/BlooperDB/API/render"
 	"github.com/BlooperDB/API/storage"
 	"github.com/BlooperDB/API/utils"
 	"github.com/graphql-go/graphql"
@@ -22,6 +26,26 @@ var enumVote = graphql.NewEnum(
 	},
 )
 
+var enumBlueprintKind = graphql.NewEnum(
+	graphql.EnumConfig{
+		Name: "BlueprintKind",
+		Values: graphql.EnumValueConfigMap{
+			"BLUEPRINT": &graphql.EnumValueConfig{
+				Value: bp.KindBlueprint,
+			},
+			"BLUEPRINT_BOOK": &graphql.EnumValueConfig{
+				Value: bp.KindBlueprintBook,
+			},
+			"DECONSTRUCTION_PLANNER": &graphql.EnumValueConfig{
+				Value: bp.KindDeconstructionPlanner,
+			},
+			"UPGRADE_PLANNER": &graphql.EnumValueConfig{
+				Value: bp.KindUpgradePlanner,
+			},
+		},
+	},
+)
+
 var graphTag = graphql.NewObject(
 	graphql.ObjectConfig{
 		Name: "Tag",
@@ -46,6 +70,13 @@ var graphComment = graphql.NewObject(
 			"revisionId": &graphql.Field{
 				Type: graphql.NewNonNull(graphql.Int),
 			},
+			"revision": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Number of the revision within its blueprint.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return utils.Source(p, "_db").(*db.Comment).GetRevision().Revision, nil
+				},
+			},
 			"createdAt": &graphql.Field{
 				Type: graphql.NewNonNull(graphql.DateTime),
 			},
@@ -55,6 +86,120 @@ var graphComment = graphql.NewObject(
 			"message": &graphql.Field{
 				Type: graphql.NewNonNull(graphql.String),
 			},
+			"parentId": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Comment this comment replies to.",
+			},
+			"depth": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "0 for top level comments.",
+			},
+			"replyCount": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return utils.Source(p, "_db").(*db.Comment).CountReplies(), nil
+				},
+			},
+		},
+	},
+)
+
+var enumRenderStatus = graphql.NewEnum(
+	graphql.EnumConfig{
+		Name: "RenderStatus",
+		Values: graphql.EnumValueConfigMap{
+			"PENDING": &graphql.EnumValueConfig{
+				Value: RenderStatusPending,
+			},
+			"RENDERING": &graphql.EnumValueConfig{
+				Value: RenderStatusRendering,
+			},
+			"READY": &graphql.EnumValueConfig{
+				Value: RenderStatusReady,
+			},
+			"FAILED": &graphql.EnumValueConfig{
+				Value: RenderStatusFailed,
+			},
+		},
+	},
+)
+
+var graphRenderVariant = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "RenderVariant",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"url": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+		},
+	},
+)
+
+var graphMaterialCount = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "MaterialCount",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"count": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Float),
+			},
+		},
+	},
+)
+
+var graphMaterials = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "Materials",
+		Fields: graphql.Fields{
+			"entities": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphMaterialCount)),
+			},
+			"tiles": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphMaterialCount)),
+			},
+			"items": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphMaterialCount)),
+			},
+			"raw": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphMaterialCount)),
+			},
+		},
+	},
+)
+
+var graphRevisionChild = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "RevisionChild",
+		Fields: graphql.Fields{
+			"index": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+			"kind": &graphql.Field{
+				Type: graphql.NewNonNull(enumBlueprintKind),
+			},
+			"label": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"description": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"blueprintString": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"thumbnail": &graphql.Field{
+				Type: graphql.String,
+			},
+			"render": &graphql.Field{
+				Type: graphql.String,
+			},
+			"renders": &graphql.Field{
+				Type: graphql.NewList(graphRenderVariant),
+			},
 		},
 	},
 )
@@ -106,18 +251,263 @@ var graphRevision = graphql.NewObject(
 			"comments": &graphql.Field{
 				Type: graphql.NewNonNull(graphql.NewList(graphComment)),
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					return dbToComments(utils.Source(p, "_db").(*db.Revision).GetComments()), nil
+					return dbToComments(utils.Source(p, "_db").(*db.Revision).GetTopLevelComments()), nil
 				},
 			},
 			"version": &graphql.Field{
 				Type: graphql.NewNonNull(graphql.Int),
 			},
-			"thumbnail": &graphql.Field{
+			"kind": &graphql.Field{
+				Type: graphql.NewNonNull(enumBlueprintKind),
+			},
+			"checksum": &graphql.Field{
 				Type: graphql.NewNonNull(graphql.String),
 			},
+			"renderStatus": &graphql.Field{
+				Type: graphql.NewNonNull(enumRenderStatus),
+			},
+			"renderedAt": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+			"renderError": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Error of the last failed render attempt.",
+			},
+			"materials": &graphql.Field{
+				Type:        graphMaterials,
+				Description: "Entity counts, tile counts and raw resources needed to build the revision.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					materials, e := revisionMaterials(utils.Source(p, "_db").(*db.Revision))
+
+					if e != nil {
+						return nil, errors.New(e.Message)
+					}
+
+					return materialsToGraph(materials), nil
+				},
+			},
+			"children": &graphql.Field{
+				Type:        graphql.NewList(graphRevisionChild),
+				Description: "Children of a blueprint book, null for other kinds.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					revision := utils.Source(p, "_db").(*db.Revision)
+
+					if revision.Kind != bp.KindBlueprintBook {
+						return nil, nil
+					}
+
+					children, e := revisionChildren(revision)
+
+					if e != nil {
+						return nil, errors.New(e.Message)
+					}
+
+					return revisionChildrenToGraph(children), nil
+				},
+			},
+			"thumbnail": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Null until the render is ready.",
+			},
 			"render": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Null until the render is ready.",
+			},
+			"renders": &graphql.Field{
+				Type:        graphql.NewList(graphRenderVariant),
+				Description: "URLs of all render variants, null until the render is ready.",
+			},
+		},
+	},
+)
+
+var graphHighlight = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name:        "Highlight",
+		Description: "Search snippets with matches wrapped in <b> tags.",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"description": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+		},
+	},
+)
+
+var graphFacetBucket = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "FacetBucket",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Id of the author of author buckets.",
+			},
+			"value": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"count": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+		},
+	},
+)
+
+var graphFacets = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "Facets",
+		Fields: graphql.Fields{
+			"tags": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphFacetBucket)),
+			},
+			"authors": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphFacetBucket)),
+				Description: "Top authors, the value is the username.",
+			},
+			"gameVersions": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphFacetBucket)),
+			},
+			"kinds": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphFacetBucket)),
+			},
+		},
+	},
+)
+
+var graphPageInfo = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+			},
+			"hasPreviousPage": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+			},
+			"startCursor": &graphql.Field{
+				Type: graphql.String,
+			},
+			"endCursor": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+	},
+)
+
+var graphPosition = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "Position",
+		Fields: graphql.Fields{
+			"x": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Float),
+			},
+			"y": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Float),
+			},
+		},
+	},
+)
+
+var graphDiffEntity = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "DiffEntity",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"position": &graphql.Field{
+				Type: graphql.NewNonNull(graphPosition),
+			},
+		},
+	},
+)
+
+var graphDiffMove = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "DiffMove",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"from": &graphql.Field{
+				Type: graphql.NewNonNull(graphPosition),
+			},
+			"to": &graphql.Field{
+				Type: graphql.NewNonNull(graphPosition),
+			},
+		},
+	},
+)
+
+var graphFieldChange = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "FieldChange",
+		Fields: graphql.Fields{
+			"field": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "direction, recipe, modules, wires or settings.",
+			},
+			"from": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Old value, the removed wires for wire changes.",
+			},
+			"to": &graphql.Field{
+				Type:        graphql.String,
+				Description: "New value, the added wires for wire changes.",
+			},
+		},
+	},
+)
+
+var graphDiffChange = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "DiffChange",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
 				Type: graphql.NewNonNull(graphql.String),
 			},
+			"position": &graphql.Field{
+				Type: graphql.NewNonNull(graphPosition),
+			},
+			"changes": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphFieldChange)),
+			},
+		},
+	},
+)
+
+var graphDiff = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "Diff",
+		Fields: graphql.Fields{
+			"from": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+			"to": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+			"child": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"added": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphDiffEntity)),
+			},
+			"removed": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphDiffEntity)),
+			},
+			"moved": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphDiffMove)),
+			},
+			"changed": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphDiffChange)),
+			},
+			"tilesAdded": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+			"tilesRemoved": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
 		},
 	},
 )
@@ -161,6 +551,77 @@ var graphBlueprint = graphql.NewObject(
 					return dbToRevision(utils.Source(p, "_db").(*db.Blueprint).GetRevision(uint(p.Args["revision"].(int))), db.GetAuthUserGraphQL(p)), nil
 				},
 			},
+			"comments": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphComment)),
+				Description: "Comments on all revisions, oldest first.",
+				Args: graphql.FieldConfigArgument{
+					"revision": &graphql.ArgumentConfig{
+						Type:        graphql.Int,
+						Description: "Only comments on this revision number.",
+					},
+					"author": &graphql.ArgumentConfig{
+						Type: graphql.Int,
+					},
+					"since": &graphql.ArgumentConfig{
+						Type:        graphql.DateTime,
+						Description: "Only comments created at or after this time.",
+					},
+					"topLevel": &graphql.ArgumentConfig{
+						Type:         graphql.Boolean,
+						DefaultValue: false,
+						Description:  "Only comments that are not replies, use replies to walk the threads.",
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					filter := db.CommentFilter{
+						TopLevel: p.Args["topLevel"].(bool),
+					}
+
+					if revision, ok := p.Args["revision"].(int); ok {
+						filter.Revision = uint(revision)
+					}
+
+					if author, ok := p.Args["author"].(int); ok {
+						filter.Author = uint(author)
+					}
+
+					if since, ok := p.Args["since"].(time.Time); ok {
+						filter.Since = &since
+					}
+
+					return dbToComments(utils.Source(p, "_db").(*db.Blueprint).GetComments(filter)), nil
+				},
+			},
+			"diff": &graphql.Field{
+				Type:        graphDiff,
+				Description: "Entity level changes between two revisions, books are compared by child.",
+				Args: graphql.FieldConfigArgument{
+					"from": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Int),
+					},
+					"to": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Int),
+					},
+					"child": &graphql.ArgumentConfig{
+						Type: graphql.Int,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var child *int
+
+					if index, ok := p.Args["child"].(int); ok {
+						child = &index
+					}
+
+					diff, e := blueprintDiff(utils.Source(p, "_db").(*db.Blueprint), uint(p.Args["from"].(int)), uint(p.Args["to"].(int)), child)
+
+					if e != nil {
+						return nil, errors.New(e.Message)
+					}
+
+					return diffToGraph(diff), nil
+				},
+			},
 			"tags": &graphql.Field{
 				Type: graphql.NewNonNull(graphql.NewList(graphTag)),
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
@@ -174,8 +635,143 @@ var graphBlueprint = graphql.NewObject(
 				Type: graphql.NewNonNull(graphql.DateTime),
 			},
 			"thumbnail": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Missing until the latest revision is rendered.",
+			},
+			"highlight": &graphql.Field{
+				Type:        graphHighlight,
+				Description: "Only available when searching.",
+			},
+			"forkedFromRevision": &graphql.Field{
+				Type:        graphRevision,
+				Description: "Revision this blueprint was forked from.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return dbToRevision(utils.Source(p, "_db").(*db.Blueprint).GetForkedFromRevision(), db.GetAuthUserGraphQL(p)), nil
+				},
+			},
+			"forkCount": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return utils.Source(p, "_db").(*db.Blueprint).CountForks(), nil
+				},
+			},
+			"favoriteCount": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return utils.Source(p, "_db").(*db.Blueprint).CountFavorites(), nil
+				},
+			},
+			"favorited": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "Whether the authenticated user favorited this blueprint, false when not authenticated.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					user := db.GetAuthUserGraphQL(p)
+
+					if user == nil {
+						return false, nil
+					}
+
+					return db.IsFavorite(user.ID, utils.Source(p, "_db").(*db.Blueprint).ID), nil
+				},
+			},
+		},
+	},
+)
+
+// Fields referring back to blueprints are added once graphBlueprint exists
+func init() {
+	graphBlueprint.AddFieldConfig("forkedFrom", &graphql.Field{
+		Type:        graphBlueprint,
+		Description: "Blueprint this blueprint was forked from.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return dbToBlueprint(utils.Source(p, "_db").(*db.Blueprint).GetForkedFrom()), nil
+		},
+	})
+
+	graphBlueprint.AddFieldConfig("forks", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphBlueprint)),
+		Description: "Newest forks first.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return dbToBlueprints(utils.Source(p, "_db").(*db.Blueprint).GetForks()), nil
+		},
+	})
+
+	graphComment.AddFieldConfig("replies", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphComment)),
+		Description: "Direct replies, oldest first.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return dbToComments(utils.Source(p, "_db").(*db.Comment).GetReplies()), nil
+		},
+	})
+}
+
+var enumCollectionVisibility = graphql.NewEnum(
+	graphql.EnumConfig{
+		Name: "CollectionVisibility",
+		Values: graphql.EnumValueConfigMap{
+			"PUBLIC": &graphql.EnumValueConfig{
+				Value: db.CollectionPublic,
+			},
+			"UNLISTED": &graphql.EnumValueConfig{
+				Value: db.CollectionUnlisted,
+			},
+			"PRIVATE": &graphql.EnumValueConfig{
+				Value: db.CollectionPrivate,
+			},
+		},
+	},
+)
+
+var graphCollection = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "Collection",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+			"user": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+			"title": &graphql.Field{
 				Type: graphql.NewNonNull(graphql.String),
 			},
+			"description": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"visibility": &graphql.Field{
+				Type: graphql.NewNonNull(enumCollectionVisibility),
+			},
+			"blueprints": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphBlueprint)),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return dbToBlueprints(utils.Source(p, "_db").(*db.Collection).GetBlueprints()), nil
+				},
+			},
+			"blueprintCount": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return utils.Source(p, "_db").(*db.Collection).CountBlueprints(), nil
+				},
+			},
+			"blueprintString": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Blueprint book of the latest revision of each blueprint.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					blueprintString, e := collectionBook(utils.Source(p, "_db").(*db.Collection))
+
+					if e != nil {
+						return nil, errors.New(e.Message)
+					}
+
+					return blueprintString, nil
+				},
+			},
+			"createdAt": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.DateTime),
+			},
+			"updatedAt": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.DateTime),
+			},
 		},
 	},
 )
@@ -200,6 +796,47 @@ var interfaceUserData = graphql.NewInterface(
 	},
 )
 
+var graphBlueprintEdge = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "BlueprintEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"node": &graphql.Field{
+				Type: graphql.NewNonNull(graphBlueprint),
+			},
+		},
+	},
+)
+
+var graphBlueprintConnection = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "BlueprintConnection",
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphBlueprintEdge)),
+			},
+			"pageInfo": &graphql.Field{
+				Type: graphql.NewNonNull(graphPageInfo),
+			},
+			"totalCount": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return db.CountBlueprints(utils.Source(p, "_search").(string), utils.Source(p, "_filter").(db.BlueprintFilter)), nil
+				},
+			},
+			"facets": &graphql.Field{
+				Type:        graphql.NewNonNull(graphFacets),
+				Description: "Tags, authors, game versions and kinds counted over all matching blueprints.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return facetsToGraph(db.SearchFacets(utils.Source(p, "_search").(string), utils.Source(p, "_filter").(db.BlueprintFilter))), nil
+				},
+			},
+		},
+	},
+)
+
 var graphPublicUser = graphql.NewObject(
 	graphql.ObjectConfig{
 		Name: "PublicUser",
@@ -222,6 +859,13 @@ var graphPublicUser = graphql.NewObject(
 					return dbToBlueprints(utils.Source(p, "_db").(*db.User).GetUserBlueprints()), nil
 				},
 			},
+			"collections": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphCollection)),
+				Description: "Public collections, newest first.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return dbToCollections(db.GetUserCollections(utils.Source(p, "_db").(*db.User).ID, false)), nil
+				},
+			},
 		},
 	},
 )
@@ -251,6 +895,20 @@ var graphPrivateUser = graphql.NewObject(
 					return dbToBlueprints(utils.Source(p, "_db").(*db.User).GetUserBlueprints()), nil
 				},
 			},
+			"collections": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphCollection)),
+				Description: "All collections including unlisted and private ones, newest first.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return dbToCollections(db.GetUserCollections(utils.Source(p, "_db").(*db.User).ID, true)), nil
+				},
+			},
+			"favorites": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphBlueprint)),
+				Description: "Favorite blueprints, most recently favorited first.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return dbToBlueprints(utils.Source(p, "_db").(*db.User).GetFavoriteBlueprints()), nil
+				},
+			},
 			"createdAt": &graphql.Field{
 				Type: graphql.NewNonNull(graphql.DateTime),
 			},
@@ -288,6 +946,15 @@ var enumBlueprintOrder = graphql.NewEnum(
 			"POPULAR": &graphql.EnumValueConfig{},
 			"TOP":     &graphql.EnumValueConfig{},
 			"NEW":     &graphql.EnumValueConfig{},
+			"BEST": &graphql.EnumValueConfig{
+				Description: "Lower bound of the Wilson score interval of thumbs up and down.",
+			},
+			"TRENDING": &graphql.EnumValueConfig{
+				Description: "Thumbs up minus thumbs down given in the last " + strconv.Itoa(db.TrendingDays) + " days, cursors keep counting up to the time of the first page.",
+			},
+			"CONTROVERSIAL": &graphql.EnumValueConfig{
+				Description: "Many ratings split evenly between thumbs up and down.",
+			},
 		},
 	},
 )
@@ -311,7 +978,7 @@ var graphQuery = graphql.NewObject(
 			"blueprints": &graphql.Field{
 				Type:        graphql.NewList(graphBlueprint),
 				Description: "Retrieve blueprints.",
-				Args: graphql.FieldConfigArgument{
+				Args: blueprintFilterArguments(graphql.FieldConfigArgument{
 					"order": &graphql.ArgumentConfig{
 						Type:         enumBlueprintOrder,
 						DefaultValue: "NORMAL",
@@ -324,18 +991,70 @@ var graphQuery = graphql.NewObject(
 						Type:         graphql.Int,
 						DefaultValue: 20,
 					},
-					"search": &graphql.ArgumentConfig{
-						Type:         graphql.String,
-						DefaultValue: "",
-					},
 					"ascending": &graphql.ArgumentConfig{
 						Type:         graphql.Boolean,
 						DefaultValue: false,
 					},
+				}),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					page := db.Page{
+						Offset: p.Args["offset"].(int),
+						Limit:  utils.MinMax(1, p.Args["count"].(int), 100),
+					}
+
+					result, err := db.ListBlueprints(p.Args["search"].(string), blueprintFilterArgs(p), p.Args["order"].(string), p.Args["ascending"].(bool), page)
+
+					if err != nil {
+						return nil, err
+					}
+
+					return dbToSearchResults(result.Blueprints), nil
 				},
+			},
+			"blueprintConnection": &graphql.Field{
+				Type:        graphBlueprintConnection,
+				Description: "Retrieve blueprints as a cursor paginated connection.",
+				Args: blueprintFilterArguments(graphql.FieldConfigArgument{
+					"order": &graphql.ArgumentConfig{
+						Type:         enumBlueprintOrder,
+						DefaultValue: "NORMAL",
+					},
+					"ascending": &graphql.ArgumentConfig{
+						Type:         graphql.Boolean,
+						DefaultValue: false,
+					},
+					"first": &graphql.ArgumentConfig{
+						Type:        graphql.Int,
+						Description: "Number of blueprints after the after cursor",
+					},
+					"after": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+					"last": &graphql.ArgumentConfig{
+						Type:        graphql.Int,
+						Description: "Number of blueprints before the before cursor",
+					},
+					"before": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+				}),
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					count := utils.MinMax(1, p.Args["count"].(int), 100)
-					return dbToBlueprints(db.FindBlueprintsDynamic(p.Args["search"].(string), p.Args["offset"].(int), count, p.Args["order"].(string), p.Args["ascending"].(bool))), nil
+					page, err := connectionPage(p)
+
+					if err != nil {
+						return nil, err
+					}
+
+					search := p.Args["search"].(string)
+					filter := blueprintFilterArgs(p)
+
+					result, err := db.ListBlueprints(search, filter, p.Args["order"].(string), p.Args["ascending"].(bool), page)
+
+					if err != nil {
+						return nil, err
+					}
+
+					return blueprintPageToConnection(result, search, filter), nil
 				},
 			},
 			"tags": &graphql.Field{
@@ -406,6 +1125,24 @@ var graphQuery = graphql.NewObject(
 					return dbToComment(db.GetCommentById(uint(p.Args["id"].(int)))), nil
 				},
 			},
+			"collection": &graphql.Field{
+				Type:        graphCollection,
+				Description: "Retrieve collection by id.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Int),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					collection, e := findCollectionById(uint(p.Args["id"].(int)), db.GetAuthUserGraphQL(p))
+
+					if e != nil {
+						return nil, nil
+					}
+
+					return dbToCollection(collection), nil
+				},
+			},
 		},
 	},
 )
@@ -491,7 +1228,9 @@ var graphMutation = graphql.NewObject(
 							return nil, errors.New("rating not found")
 						}
 
-						rating.Delete()
+						if err := rating.Delete(); err != nil {
+							return nil, err
+						}
 					} else {
 						thumbsUp := true
 
@@ -503,7 +1242,53 @@ var graphMutation = graphql.NewObject(
 						rating.RevisionID = revision.ID
 						rating.ThumbsUp = thumbsUp
 						rating.DeletedAt = nil
-						rating.Save()
+
+						if err := rating.Save(); err != nil {
+							return nil, err
+						}
+					}
+
+					return true, nil
+				},
+			},
+			"favoriteBlueprint": &graphql.Field{
+				Type:        graphql.Boolean,
+				Description: "Add a blueprint to or remove it from the favorites of the user.",
+				Args: graphql.FieldConfigArgument{
+					"blueprint": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Int),
+					},
+					"favorite": &graphql.ArgumentConfig{
+						Type:         graphql.Boolean,
+						DefaultValue: true,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					user := db.GetAuthUserGraphQL(p)
+
+					if user == nil {
+						return nil, errors.New("invalid token")
+					}
+
+					blueprint := db.GetBlueprintById(uint(p.Args["blueprint"].(int)))
+
+					if blueprint == nil {
+						return nil, errors.New("blueprint not found")
+					}
+
+					favorite := db.FindFavorite(user.ID, blueprint.ID)
+
+					if !p.Args["favorite"].(bool) {
+						if favorite.ID == 0 || favorite.DeletedAt != nil {
+							return nil, errors.New("favorite not found")
+						}
+
+						favorite.Delete()
+					} else {
+						favorite.UserID = user.ID
+						favorite.BlueprintID = blueprint.ID
+						favorite.DeletedAt = nil
+						favorite.Save()
 					}
 
 					return true, nil
@@ -540,19 +1325,25 @@ var graphMutation = graphql.NewObject(
 						return nil, errors.New("unable to mutate this blueprint")
 					}
 
-					i := blueprint.IncrementAndGetRevision()
-
 					blueprintString := p.Args["blueprint"].(string)
 					changes := p.Args["changes"].(string)
 
-					bpVersion, _ := strconv.Atoi(blueprintString[0:1])
+					container, err := bp.Decode(blueprintString)
+
+					if err != nil {
+						return nil, errors.New("invalid blueprint string")
+					}
 
 					sha265 := utils.SHA265(blueprintString)
 
-					if db.FindRevisionByChecksum(sha265) != nil {
+					if db.FindBlueprintRevisionByChecksum(blueprint.ID, sha265) != nil {
 						return nil, errors.New("blueprint already exists")
 					}
 
+					i := blueprint.IncrementAndGetRevision()
+
+					bpVersion, _ := strconv.Atoi(blueprintString[0:1])
+
 					revision := &db.Revision{
 						BlueprintID:       blueprint.ID,
 						Revision:          i,
@@ -561,10 +1352,8 @@ var graphMutation = graphql.NewObject(
 						BlueprintChecksum: sha265,
 					}
 
-					revision.Save()
-
-					storage.SaveRevision(revision.ID, blueprintString)
-					go storage.RenderAndSaveAndUpdateBlueprint(blueprintString, revision)
+					saveRevision(revision, container, blueprintString)
+					revisionsChanged(blueprint.ID)
 
 					return dbToRevision(revision, db.GetAuthUserGraphQL(p)), nil
 				},
@@ -599,8 +1388,11 @@ var graphMutation = graphql.NewObject(
 						return nil, errors.New("unable to mutate this blueprint")
 					}
 
-					revision.Changes = p.Args["changes"].(string)
-					revision.Save()
+					if changes := p.Args["changes"].(string); revision.Changes != changes {
+						revision.Changes = changes
+						revision.Save()
+						db.UpdateBlueprintSearch(revision.BlueprintID)
+					}
 
 					return dbToRevision(revision, db.GetAuthUserGraphQL(p)), nil
 				},
@@ -632,10 +1424,12 @@ var graphMutation = graphql.NewObject(
 						return nil, errors.New("unable to mutate this blueprint")
 					}
 
-					revision.Delete()
+					removeRevision(revision)
 
 					if blueprint.CountRevisions() == 0 {
 						blueprint.Delete()
+					} else {
+						revisionsChanged(blueprint.ID)
 					}
 
 					return true, nil
@@ -670,12 +1464,14 @@ var graphMutation = graphql.NewObject(
 					blueprintString := p.Args["blueprint"].(string)
 					tags := p.Args["tags"].([]string)
 
-					sha265 := utils.SHA265(blueprintString)
+					container, err := bp.Decode(blueprintString)
 
-					if db.FindRevisionByChecksum(sha265) != nil {
-						return nil, errors.New("blueprint already exists")
+					if err != nil {
+						return nil, errors.New("invalid blueprint string")
 					}
 
+					sha265 := utils.SHA265(blueprintString)
+
 					blueprint := &db.Blueprint{
 						UserID:       user.ID,
 						Name:         name,
@@ -695,10 +1491,7 @@ var graphMutation = graphql.NewObject(
 						BlueprintChecksum: sha265,
 					}
 
-					revision.Save()
-
-					storage.SaveRevision(revision.ID, blueprintString)
-					go storage.RenderAndSaveAndUpdateBlueprint(blueprintString, revision)
+					saveRevision(revision, container, blueprintString)
 
 					for _, tag := range tags {
 
@@ -720,9 +1513,58 @@ var graphMutation = graphql.NewObject(
 						bt.Save()
 					}
 
+					revisionsChanged(blueprint.ID)
+
 					return dbToBlueprint(blueprint), nil
 				},
 			},
+			"forkBlueprint": &graphql.Field{
+				Type:        graphBlueprint,
+				Description: "Fork a revision of another user's blueprint, the latest one if no revision is given.",
+				Args: graphql.FieldConfigArgument{
+					"blueprintId": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Int),
+					},
+					"revision": &graphql.ArgumentConfig{
+						Type: graphql.Int,
+					},
+					"name": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+					"description": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					user := db.GetAuthUserGraphQL(p)
+
+					if user == nil {
+						return nil, errors.New("invalid token")
+					}
+
+					revision, _ := p.Args["revision"].(int)
+					name, _ := p.Args["name"].(string)
+					description, _ := p.Args["description"].(string)
+
+					if name != "" && len(name) < 5 {
+						return nil, errors.New("name too short")
+					}
+
+					source := db.GetBlueprintById(uint(p.Args["blueprintId"].(int)))
+
+					if source == nil {
+						return nil, errors.New("blueprint not found")
+					}
+
+					fork, _, e := forkBlueprint(user, source, uint(revision), name, description)
+
+					if e != nil {
+						return nil, errors.New(e.Message)
+					}
+
+					return dbToBlueprint(fork), nil
+				},
+			},
 			"updateBlueprint": &graphql.Field{
 				Type:        graphBlueprint,
 				Description: "Update a blueprint.",
@@ -761,6 +1603,8 @@ var graphMutation = graphql.NewObject(
 						return nil, errors.New("unable to mutate this blueprint")
 					}
 
+					indexed := blueprint.Name != name || blueprint.Description != description || !sameTags(blueprint.GetTags(), tags)
+
 					for _, t := range blueprint.GetTags() {
 						bt := db.BlueprintTag{
 							BlueprintId: blueprint.ID,
@@ -790,6 +1634,10 @@ var graphMutation = graphql.NewObject(
 
 					blueprint.Save()
 
+					if indexed {
+						db.UpdateBlueprintSearch(blueprint.ID)
+					}
+
 					return dbToBlueprint(blueprint), nil
 				},
 			},
@@ -818,21 +1666,135 @@ var graphMutation = graphql.NewObject(
 						return nil, errors.New("unable to mutate this blueprint")
 					}
 
-					blueprint.Delete()
+					removeBlueprint(blueprint)
 
 					return true, nil
 				},
 			},
 			"addComment": &graphql.Field{
 				Type:        graphComment,
-				Description: "Add a comment.",
+				Description: "Add a comment, or a reply to the parent comment.",
+				Args: graphql.FieldConfigArgument{
+					"revision": &graphql.ArgumentConfig{
+						Type:        graphql.Int,
+						Description: "Required unless replying.",
+					},
+					"parent": &graphql.ArgumentConfig{
+						Type: graphql.Int,
+					},
+					"message": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					user := db.GetAuthUserGraphQL(p)
+
+					if user == nil {
+						return nil, errors.New("invalid token")
+					}
+
+					var revision, parent uint
+
+					if r, ok := p.Args["revision"]; ok {
+						revision = uint(r.(int))
+					}
+
+					if r, ok := p.Args["parent"]; ok {
+						parent = uint(r.(int))
+					}
+
+					comment, e := newComment(user, revision, parent, p.Args["message"].(string))
+
+					if e != nil {
+						return nil, errors.New(e.Message)
+					}
+
+					return dbToComment(comment), nil
+				},
+			},
+			"updateComment": &graphql.Field{
+				Type:        graphComment,
+				Description: "Update a comment.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Int),
+					},
+					"message": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					user := db.GetAuthUserGraphQL(p)
+
+					if user == nil {
+						return nil, errors.New("invalid token")
+					}
+
+					comment := db.GetCommentById(uint(p.Args["id"].(int)))
+
+					if comment == nil {
+						return nil, errors.New("comment not found")
+					}
+
+					if comment.ID != user.ID {
+						return nil, errors.New("unable to mutate this comment")
+					}
+
+					comment.Message = p.Args["message"].(string)
+					comment.Save()
+
+					return dbToComment(comment), nil
+				},
+			},
+			"deleteComment": &graphql.Field{
+				Type:        graphql.Boolean,
+				Description: "Delete a comment.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Int),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					user := db.GetAuthUserGraphQL(p)
+
+					if user == nil {
+						return nil, errors.New("invalid token")
+					}
+
+					comment := db.GetCommentById(uint(p.Args["id"].(int)))
+
+					if comment == nil {
+						return nil, errors.New("comment not found")
+					}
+
+					if comment.ID != user.ID {
+						return nil, errors.New("unable to mutate this comment")
+					}
+
+					comment.Delete()
+
+					return true, nil
+				},
+			},
+			"addCollection": &graphql.Field{
+				Type:        graphCollection,
+				Description: "Add a collection.",
 				Args: graphql.FieldConfigArgument{
-					"revision": &graphql.ArgumentConfig{
-						Type: graphql.NewNonNull(graphql.Int),
-					},
-					"message": &graphql.ArgumentConfig{
+					"title": &graphql.ArgumentConfig{
 						Type: graphql.NewNonNull(graphql.String),
 					},
+					"description": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
+					"visibility": &graphql.ArgumentConfig{
+						Type:         enumCollectionVisibility,
+						DefaultValue: db.CollectionPublic,
+					},
+					"blueprints": &graphql.ArgumentConfig{
+						Type:        graphql.NewList(graphql.NewNonNull(graphql.Int)),
+						Description: "Blueprint ids in collection order",
+					},
 				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 					user := db.GetAuthUserGraphQL(p)
@@ -841,26 +1803,49 @@ var graphMutation = graphql.NewObject(
 						return nil, errors.New("invalid token")
 					}
 
-					comment := &db.Comment{
-						RevisionID: uint(p.Args["revision"].(int)),
-						UserID:     user.ID,
-						Message:    p.Args["message"].(string),
+					title := p.Args["title"].(string)
+					blueprints := uintArgs(p.Args["blueprints"])
+
+					if len(title) < 3 {
+						return nil, errors.New("title too short")
 					}
 
-					comment.Save()
+					if e := validateCollectionBlueprints(blueprints); e != nil {
+						return nil, errors.New(e.Message)
+					}
 
-					return dbToComment(comment), nil
+					collection := &db.Collection{
+						UserID:      user.ID,
+						Title:       title,
+						Description: p.Args["description"].(string),
+						Visibility:  p.Args["visibility"].(string),
+					}
+
+					collection.Save()
+					collection.SetBlueprints(blueprints)
+
+					return dbToCollection(collection), nil
 				},
 			},
-			"updateComment": &graphql.Field{
-				Type:        graphComment,
-				Description: "Update a comment.",
+			"updateCollection": &graphql.Field{
+				Type:        graphCollection,
+				Description: "Update a collection, blueprints replace the whole list when given.",
 				Args: graphql.FieldConfigArgument{
 					"id": &graphql.ArgumentConfig{
 						Type: graphql.NewNonNull(graphql.Int),
 					},
-					"message": &graphql.ArgumentConfig{
-						Type: graphql.NewNonNull(graphql.String),
+					"title": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+					"description": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+					"visibility": &graphql.ArgumentConfig{
+						Type: enumCollectionVisibility,
+					},
+					"blueprints": &graphql.ArgumentConfig{
+						Type:        graphql.NewList(graphql.NewNonNull(graphql.Int)),
+						Description: "Blueprint ids in collection order",
 					},
 				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
@@ -870,25 +1855,50 @@ var graphMutation = graphql.NewObject(
 						return nil, errors.New("invalid token")
 					}
 
-					comment := db.GetCommentById(uint(p.Args["id"].(int)))
+					collection := db.GetCollectionById(uint(p.Args["id"].(int)))
 
-					if comment == nil {
-						return nil, errors.New("comment not found")
+					if collection == nil || !collection.VisibleTo(user) {
+						return nil, errors.New("collection not found")
 					}
 
-					if comment.ID != user.ID {
-						return nil, errors.New("unable to mutate this comment")
+					if collection.UserID != user.ID {
+						return nil, errors.New("unable to mutate this collection")
 					}
 
-					comment.Message = p.Args["message"].(string)
-					comment.Save()
+					if title, ok := p.Args["title"].(string); ok {
+						if len(title) < 3 {
+							return nil, errors.New("title too short")
+						}
 
-					return dbToComment(comment), nil
+						collection.Title = title
+					}
+
+					if description, ok := p.Args["description"].(string); ok {
+						collection.Description = description
+					}
+
+					if visibility, ok := p.Args["visibility"].(string); ok {
+						collection.Visibility = visibility
+					}
+
+					if _, ok := p.Args["blueprints"]; ok {
+						blueprints := uintArgs(p.Args["blueprints"])
+
+						if e := validateCollectionBlueprints(blueprints); e != nil {
+							return nil, errors.New(e.Message)
+						}
+
+						collection.SetBlueprints(blueprints)
+					}
+
+					collection.Save()
+
+					return dbToCollection(collection), nil
 				},
 			},
-			"deleteComment": &graphql.Field{
+			"deleteCollection": &graphql.Field{
 				Type:        graphql.Boolean,
-				Description: "Delete a comment.",
+				Description: "Delete a collection.",
 				Args: graphql.FieldConfigArgument{
 					"id": &graphql.ArgumentConfig{
 						Type: graphql.NewNonNull(graphql.Int),
@@ -901,17 +1911,17 @@ var graphMutation = graphql.NewObject(
 						return nil, errors.New("invalid token")
 					}
 
-					comment := db.GetCommentById(uint(p.Args["id"].(int)))
+					collection := db.GetCollectionById(uint(p.Args["id"].(int)))
 
-					if comment == nil {
-						return nil, errors.New("comment not found")
+					if collection == nil || !collection.VisibleTo(user) {
+						return nil, errors.New("collection not found")
 					}
 
-					if comment.ID != user.ID {
-						return nil, errors.New("unable to mutate this comment")
+					if collection.UserID != user.ID {
+						return nil, errors.New("unable to mutate this collection")
 					}
 
-					comment.Delete()
+					collection.Delete()
 
 					return true, nil
 				},
@@ -934,12 +1944,199 @@ func GetSchema() *graphql.Schema {
 	return &schema
 }
 
+// blueprintFilterArguments adds the search and filter arguments shared by
+// blueprints and blueprintConnection to args
+func blueprintFilterArguments(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	args["search"] = &graphql.ArgumentConfig{
+		Type:         graphql.String,
+		DefaultValue: "",
+	}
+	args["hasEntity"] = &graphql.ArgumentConfig{
+		Type:        graphql.NewList(graphql.NewNonNull(graphql.String)),
+		Description: "Only blueprints containing all of these entities",
+	}
+	args["withoutEntity"] = &graphql.ArgumentConfig{
+		Type:        graphql.NewList(graphql.NewNonNull(graphql.String)),
+		Description: "Only blueprints containing none of these entities",
+	}
+	args["maxWidth"] = &graphql.ArgumentConfig{
+		Type:         graphql.Int,
+		DefaultValue: 0,
+	}
+	args["maxHeight"] = &graphql.ArgumentConfig{
+		Type:         graphql.Int,
+		DefaultValue: 0,
+	}
+	args["gameVersion"] = &graphql.ArgumentConfig{
+		Type:         graphql.String,
+		Description:  "Exact game version or prefix such as 0.16",
+		DefaultValue: "",
+	}
+	args["tag"] = &graphql.ArgumentConfig{
+		Type:        graphql.NewList(graphql.NewNonNull(graphql.String)),
+		Description: "Only blueprints tagged with all of these tags",
+	}
+	args["author"] = &graphql.ArgumentConfig{
+		Type:         graphql.Int,
+		Description:  "Only blueprints of this user",
+		DefaultValue: 0,
+	}
+	args["kind"] = &graphql.ArgumentConfig{
+		Type:        graphql.NewList(graphql.NewNonNull(enumBlueprintKind)),
+		Description: "Only blueprints whose latest revision is one of these kinds",
+	}
+
+	return args
+}
+
+func blueprintFilterArgs(p graphql.ResolveParams) db.BlueprintFilter {
+	return db.BlueprintFilter{
+		HasEntities:     stringArgs(p.Args["hasEntity"]),
+		WithoutEntities: stringArgs(p.Args["withoutEntity"]),
+		MaxWidth:        p.Args["maxWidth"].(int),
+		MaxHeight:       p.Args["maxHeight"].(int),
+		GameVersion:     p.Args["gameVersion"].(string),
+		Tags:            stringArgs(p.Args["tag"]),
+		Author:          uint(p.Args["author"].(int)),
+		Kinds:           stringArgs(p.Args["kind"]),
+	}
+}
+
+// connectionPage reads the first/after and last/before arguments of a
+// connection, paging backwards if before is given
+func connectionPage(p graphql.ResolveParams) (db.Page, error) {
+	page := db.Page{
+		Limit: 20,
+	}
+
+	if first, ok := p.Args["first"].(int); ok {
+		page.Limit = first
+	}
+
+	token, _ := p.Args["after"].(string)
+	before, _ := p.Args["before"].(string)
+
+	if before != "" {
+		token = before
+
+		if last, ok := p.Args["last"].(int); ok {
+			page.Limit = last
+		}
+	}
+
+	page.Limit = utils.MinMax(1, page.Limit, 100)
+
+	if token == "" {
+		return page, nil
+	}
+
+	cursor, err := db.DecodeCursor(token)
+
+	if err != nil {
+		return page, err
+	}
+
+	cursor.Before = before != ""
+	page.Cursor = cursor
+
+	return page, nil
+}
+
+func blueprintPageToConnection(page *db.BlueprintPage, search string, filter db.BlueprintFilter) map[string]interface{} {
+	nodes := dbToSearchResults(page.Blueprints)
+	edges := make([]interface{}, len(nodes))
+
+	for i, node := range nodes {
+		edges[i] = map[string]interface{}{
+			"cursor": page.Cursor(i).Encode(),
+			"node":   node,
+		}
+	}
+
+	pageInfo := map[string]interface{}{
+		"hasNextPage":     page.Next != nil,
+		"hasPreviousPage": page.Prev != nil,
+	}
+
+	if len(edges) > 0 {
+		pageInfo["startCursor"] = edges[0].(map[string]interface{})["cursor"]
+		pageInfo["endCursor"] = edges[len(edges)-1].(map[string]interface{})["cursor"]
+	}
+
+	return map[string]interface{}{
+		"edges":    edges,
+		"pageInfo": pageInfo,
+		"_search":  search,
+		"_filter":  filter,
+	}
+}
+
+func facetsToGraph(facets *db.Facets) map[string]interface{} {
+	return map[string]interface{}{
+		"tags":         facetBucketsToGraph(facets.Tags),
+		"authors":      facetBucketsToGraph(facets.Authors),
+		"gameVersions": facetBucketsToGraph(facets.GameVersions),
+		"kinds":        facetBucketsToGraph(facets.Kinds),
+	}
+}
+
+func facetBucketsToGraph(buckets []db.FacetBucket) []interface{} {
+	result := make([]interface{}, len(buckets))
+
+	for i, bucket := range buckets {
+		graph := map[string]interface{}{
+			"value": bucket.Value,
+			"count": bucket.Count,
+		}
+
+		if bucket.ID != 0 {
+			graph["id"] = bucket.ID
+		}
+
+		result[i] = graph
+	}
+
+	return result
+}
+
+func uintArgs(arg interface{}) []uint {
+	list, _ := arg.([]interface{})
+	result := make([]uint, 0, len(list))
+
+	for _, value := range list {
+		if i, ok := value.(int); ok {
+			result = append(result, uint(i))
+		}
+	}
+
+	return result
+}
+
+func stringArgs(arg interface{}) []string {
+	list, _ := arg.([]interface{})
+	result := make([]string, 0, len(list))
+
+	for _, value := range list {
+		if s, ok := value.(string); ok {
+			result = append(result, s)
+		}
+	}
+
+	return result
+}
+
 func dbToBlueprint(blueprint *db.Blueprint) interface{} {
 	if blueprint == nil {
 		return nil
 	}
 
-	return map[string]interface{}{
+	return dbToBlueprintThumbnail(blueprint, blueprintThumbnail(blueprint))
+}
+
+// dbToBlueprintThumbnail is dbToBlueprint with the thumbnail already looked
+// up, an empty thumbnail is left out
+func dbToBlueprintThumbnail(blueprint *db.Blueprint, thumbnail string) map[string]interface{} {
+	result := map[string]interface{}{
 		"_db":         blueprint,
 		"id":          blueprint.ID,
 		"user":        blueprint.UserID,
@@ -947,15 +2144,54 @@ func dbToBlueprint(blueprint *db.Blueprint) interface{} {
 		"description": blueprint.Description,
 		"createdAt":   blueprint.CreatedAt,
 		"updatedAt":   blueprint.UpdatedAt,
-		"thumbnail":   storage.PublicURL + "/" + storage.BlueprintRenderBucket + "/" + blueprint.GetThumbnail() + "-thumbnail.png",
 	}
+
+	if thumbnail != "" {
+		result["thumbnail"] = thumbnail
+	}
+
+	return result
 }
 
 func dbToBlueprints(blueprints []*db.Blueprint) []interface{} {
 	var result []interface{}
 
+	thumbnails := blueprintThumbnails(blueprints)
+
 	for _, blueprint := range blueprints {
-		result = append(result, dbToBlueprint(blueprint))
+		if blueprint == nil {
+			result = append(result, nil)
+			continue
+		}
+
+		result = append(result, dbToBlueprintThumbnail(blueprint, thumbnails[blueprint.ID]))
+	}
+
+	return result
+}
+
+func dbToSearchResults(results []*db.BlueprintSearchResult) []interface{} {
+	var result []interface{}
+
+	blueprints := make([]*db.Blueprint, len(results))
+
+	for i, r := range results {
+		blueprints[i] = &r.Blueprint
+	}
+
+	thumbnails := blueprintThumbnails(blueprints)
+
+	for _, r := range results {
+		blueprint := dbToBlueprintThumbnail(&r.Blueprint, thumbnails[r.Blueprint.ID])
+
+		if r.HighlightName != "" || r.HighlightDescription != "" {
+			blueprint["highlight"] = map[string]interface{}{
+				"name":        r.HighlightName,
+				"description": r.HighlightDescription,
+			}
+		}
+
+		result = append(result, blueprint)
 	}
 
 	return result
@@ -991,6 +2227,11 @@ func dbToRevision(revision *db.Revision, user *db.User) interface{} {
 		return nil
 	}
 
+	return dbToRevisionRender(revision, user, revisionRender(revision))
+}
+
+// dbToRevisionRender is dbToRevision with the render progress already loaded
+func dbToRevisionRender(revision *db.Revision, user *db.User, state RevisionRender) interface{} {
 	ratings := revision.GetRatings()
 	thumbsUp, thumbsDown, userVote := 0, 0, 0
 
@@ -1010,31 +2251,219 @@ func dbToRevision(revision *db.Revision, user *db.User) interface{} {
 		}
 	}
 
-	baseRenderStorageURL := storage.PublicURL + "/" + storage.BlueprintRenderBucket + "/" + revision.BlueprintChecksum
+	result := map[string]interface{}{
+		"_db":          revision,
+		"id":           revision.ID,
+		"revision":     revision.Revision,
+		"changes":      revision.Changes,
+		"createdAt":    revision.CreatedAt,
+		"updatedAt":    revision.UpdatedAt,
+		"blueprintId":  revision.BlueprintID,
+		"blueprint":    storage.URL(storage.BlueprintStringBucket, revision.BlueprintChecksum),
+		"thumbsUp":     thumbsUp,
+		"thumbsDown":   thumbsDown,
+		"userVote":     userVote,
+		"version":      revision.BlueprintVersion,
+		"kind":         revision.Kind,
+		"checksum":     revision.BlueprintChecksum,
+		"renderStatus": state.Status,
+	}
+
+	if state.RenderedAt != nil {
+		result["renderedAt"] = *state.RenderedAt
+	}
 
-	return map[string]interface{}{
-		"_db":         revision,
-		"id":          revision.ID,
-		"revision":    revision.Revision,
-		"changes":     revision.Changes,
-		"createdAt":   revision.CreatedAt,
-		"updatedAt":   revision.UpdatedAt,
-		"blueprintId": revision.BlueprintID,
-		"blueprint":   storage.PublicURL + "/" + storage.BlueprintStringBucket + "/" + storage.RevisionToString(revision.ID),
-		"thumbsUp":    thumbsUp,
-		"thumbsDown":  thumbsDown,
-		"userVote":    userVote,
-		"version":     revision.BlueprintVersion,
-		"thumbnail":   baseRenderStorageURL + "-thumbnail.png",
-		"render":      baseRenderStorageURL + ".png",
+	if state.Error != "" {
+		result["renderError"] = state.Error
+	}
+
+	if state.Thumbnail != "" {
+		result["thumbnail"] = state.Thumbnail
+	}
+
+	if state.Render != "" {
+		result["render"] = state.Render
+	}
+
+	if state.Renders != nil {
+		result["renders"] = rendersToGraph(state.Renders)
 	}
+
+	return result
 }
 
 func dbToRevisions(revisions []*db.Revision, user *db.User) []interface{} {
 	var result []interface{}
 
+	states := revisionRenders(revisions)
+
 	for _, revision := range revisions {
-		result = append(result, dbToRevision(revision, user))
+		if revision == nil || revision.DeletedAt != nil {
+			result = append(result, nil)
+			continue
+		}
+
+		result = append(result, dbToRevisionRender(revision, user, states[revision.ID]))
+	}
+
+	return result
+}
+
+func diffToGraph(diff *BlueprintDiffResponse) interface{} {
+	position := func(position bp.Position) interface{} {
+		return map[string]interface{}{
+			"x": position.X,
+			"y": position.Y,
+		}
+	}
+
+	entities := func(entities []bp.DiffEntity) []interface{} {
+		result := make([]interface{}, len(entities))
+
+		for i, entity := range entities {
+			result[i] = map[string]interface{}{
+				"name":     entity.Name,
+				"position": position(entity.Position),
+			}
+		}
+
+		return result
+	}
+
+	moved := make([]interface{}, len(diff.Moved))
+
+	for i, move := range diff.Moved {
+		moved[i] = map[string]interface{}{
+			"name": move.Name,
+			"from": position(move.From),
+			"to":   position(move.To),
+		}
+	}
+
+	changed := make([]interface{}, len(diff.Changed))
+
+	for i, change := range diff.Changed {
+		changes := make([]interface{}, len(change.Changes))
+
+		for j, field := range change.Changes {
+			changes[j] = map[string]interface{}{
+				"field": field.Field,
+				"from":  field.From,
+				"to":    field.To,
+			}
+		}
+
+		changed[i] = map[string]interface{}{
+			"name":     change.Name,
+			"position": position(change.Position),
+			"changes":  changes,
+		}
+	}
+
+	result := map[string]interface{}{
+		"from":         diff.From,
+		"to":           diff.To,
+		"added":        entities(diff.Added),
+		"removed":      entities(diff.Removed),
+		"moved":        moved,
+		"changed":      changed,
+		"tilesAdded":   diff.TilesAdded,
+		"tilesRemoved": diff.TilesRemoved,
+	}
+
+	if diff.Child != nil {
+		result["child"] = *diff.Child
+	}
+
+	return result
+}
+
+func materialsToGraph(materials *RevisionMaterials) interface{} {
+	return map[string]interface{}{
+		"entities": countsToGraph(intCounts(materials.Entities)),
+		"tiles":    countsToGraph(intCounts(materials.Tiles)),
+		"items":    countsToGraph(intCounts(materials.Items)),
+		"raw":      countsToGraph(materials.Raw),
+	}
+}
+
+func intCounts(counts map[string]int) map[string]float64 {
+	result := make(map[string]float64, len(counts))
+
+	for name, count := range counts {
+		result[name] = float64(count)
+	}
+
+	return result
+}
+
+func countsToGraph(counts map[string]float64) []interface{} {
+	names := make([]string, 0, len(counts))
+
+	for name := range counts {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	result := make([]interface{}, len(names))
+
+	for i, name := range names {
+		result[i] = map[string]interface{}{
+			"name":  name,
+			"count": counts[name],
+		}
+	}
+
+	return result
+}
+
+func revisionChildrenToGraph(children []*RevisionChild) []interface{} {
+	var result []interface{}
+
+	for _, child := range children {
+		graphChild := map[string]interface{}{
+			"index":           child.Index,
+			"kind":            child.Kind,
+			"label":           child.Label,
+			"description":     child.Description,
+			"blueprintString": child.BlueprintString,
+		}
+
+		if child.Thumbnail != "" {
+			graphChild["thumbnail"] = child.Thumbnail
+		}
+
+		if child.Render != "" {
+			graphChild["render"] = child.Render
+		}
+
+		if child.Renders != nil {
+			graphChild["renders"] = rendersToGraph(child.Renders)
+		}
+
+		result = append(result, graphChild)
+	}
+
+	return result
+}
+
+// rendersToGraph lists the variant URLs in the configured variant order
+func rendersToGraph(renders map[string]string) []interface{} {
+	var result []interface{}
+
+	for _, variant := range render.Variants {
+		if url, ok := renders[variant.Name]; ok {
+			result = append(result, map[string]interface{}{
+				"name": variant.Name,
+				"url":  url,
+			})
+		}
 	}
 
 	return result
@@ -1053,6 +2482,8 @@ func dbToComment(comment *db.Comment) interface{} {
 		"updatedAt":  comment.UpdatedAt,
 		"message":    comment.Message,
 		"revisionId": comment.RevisionID,
+		"parentId":   comment.ParentID,
+		"depth":      comment.Depth,
 	}
 }
 
@@ -1092,3 +2523,30 @@ func dbToPrivateUser(user *db.User) interface{} {
 
 	return u
 }
+
+func dbToCollection(collection *db.Collection) interface{} {
+	if collection == nil {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"_db":         collection,
+		"id":          collection.ID,
+		"user":        collection.UserID,
+		"title":       collection.Title,
+		"description": collection.Description,
+		"visibility":  collection.Visibility,
+		"createdAt":   collection.CreatedAt,
+		"updatedAt":   collection.UpdatedAt,
+	}
+}
+
+func dbToCollections(collections []*db.Collection) []interface{} {
+	result := make([]interface{}, len(collections))
+
+	for i, collection := range collections {
+		result[i] = dbToCollection(collection)
+	}
+
+	return result
+}
@@ -0,0 +1,161 @@
+package nodes
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BlooperDB/API/api"
+	"github.com/BlooperDB/API/render"
+	"github.com/BlooperDB/API/storage"
+	"github.com/BlooperDB/API/utils"
+	"github.com/gorilla/mux"
+)
+
+var checksumRegex = regexp.MustCompile("^[0-9a-f]{64}$")
+
+// Images never change for a checksum until it is rendered again
+const renderCacheControl = "public, max-age=86400"
+
+func RegisterRenderRoutes(router api.RegisterRawRoute) {
+	router("GET", "/render/{checksum}", getRender)
+	router("GET", "/blueprint/{blueprint}/diff/image", getBlueprintDiffImage)
+}
+
+/*
+Get a render of a blueprint string resized to fit width x height in the requested format
+*/
+func getRender(w http.ResponseWriter, r *http.Request) *utils.ErrorResponse {
+	checksum := mux.Vars(r)["checksum"]
+
+	if !checksumRegex.MatchString(checksum) {
+		return &utils.Error_render_not_found
+	}
+
+	query := r.URL.Query()
+
+	width, e := parseImageSize(query.Get("width"))
+
+	if e != nil {
+		return e
+	}
+
+	height, e := parseImageSize(query.Get("height"))
+
+	if e != nil {
+		return e
+	}
+
+	format := query.Get("format")
+
+	if format == "" {
+		format = negotiateFormat(r)
+	}
+
+	data, err := render.Image(checksum, query.Get("variant"), width, height, format)
+
+	switch err {
+	case nil:
+	case render.ErrUnknownVariant:
+		return &utils.Error_invalid_request_data
+	case render.ErrUnsupportedFormat:
+		return &utils.Error_unsupported_image_format
+	case storage.ErrNotFound:
+		return &utils.Error_render_not_found
+	default:
+		return &utils.Error_internal_error
+	}
+
+	writeImage(w, r, data, format)
+
+	return nil
+}
+
+/*
+Get an image of the new revision highlighting added entities in green, changed and moved ones in yellow and removed ones in red
+*/
+func getBlueprintDiffImage(w http.ResponseWriter, r *http.Request) *utils.ErrorResponse {
+	blueprint, e := parseBlueprint(r)
+
+	if e != nil {
+		return e
+	}
+
+	from, to, child, e := parseDiffQuery(r)
+
+	if e != nil {
+		return e
+	}
+
+	pair, e := findDiffPair(blueprint, from, to, child)
+
+	if e != nil {
+		return e
+	}
+
+	format := r.URL.Query().Get("format")
+
+	if format == "" {
+		format = negotiateFormat(r)
+	}
+
+	data, err := render.Diff(pair.fromRevision.BlueprintChecksum, pair.toRevision.BlueprintChecksum, child,
+		pair.fromBlueprint, pair.toBlueprint, format)
+
+	switch err {
+	case nil:
+	case render.ErrUnsupportedFormat:
+		return &utils.Error_unsupported_image_format
+	case render.ErrTooLarge:
+		return &utils.Error_blueprint_too_large
+	default:
+		return &utils.Error_internal_error
+	}
+
+	writeImage(w, r, data, format)
+
+	return nil
+}
+
+// writeImage sends the image with caching headers, clients holding the same
+// image get a 304
+func writeImage(w http.ResponseWriter, r *http.Request, data []byte, format string) {
+	etag := `"` + utils.SHA265(string(data)) + `"`
+
+	w.Header().Set("Cache-Control", renderCacheControl)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Vary", "Accept")
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", render.ImageFormats[format])
+	w.Write(data)
+}
+
+// parseImageSize parses a width or height, missing sizes are unlimited
+func parseImageSize(value string) (int, *utils.ErrorResponse) {
+	if value == "" {
+		return 0, nil
+	}
+
+	size, err := strconv.Atoi(value)
+
+	if err != nil || size < 1 || size > render.MaxImageSize {
+		return 0, &utils.Error_invalid_image_size
+	}
+
+	return size, nil
+}
+
+// negotiateFormat picks webp for clients accepting it and png otherwise
+func negotiateFormat(r *http.Request) string {
+	if strings.Contains(r.Header.Get("Accept"), "image/webp") {
+		return "webp"
+	}
+
+	return "png"
+}
@@ -1,13 +1,16 @@
 package nodes
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
 	"strconv"
 
 	"github.com/BlooperDB/API/api"
+	bp "github.com/BlooperDB/API/blueprint"
 	"github.com/BlooperDB/API/db"
+	"github.com/BlooperDB/API/render"
 	"github.com/BlooperDB/API/storage"
 	"github.com/BlooperDB/API/utils"
 	"github.com/gorilla/mux"
@@ -26,8 +29,10 @@ type Revision struct {
 	UserVote    int        `json:"user-vote"`
 	Comments    []*Comment `json:"comments,omitempty"`
 	Version     int        `json:"version"`
-	Thumbnail   string     `json:"thumbnail"`
-	Render      string     `json:"render"`
+	Kind        string     `json:"kind"`
+	Checksum    string     `json:"checksum"`
+
+	RevisionRender
 }
 
 func RegisterRevisionRoutes(router api.RegisterRoute) {
@@ -38,6 +43,13 @@ func RegisterRevisionRoutes(router api.RegisterRoute) {
 
 	router("GET", "/revision/{revision}/comments", getRevisionComments)
 
+	router("GET", "/revision/{revision}/materials", getRevisionMaterials)
+
+	router("GET", "/revision/{revision}/render", getRevisionRender)
+
+	router("GET", "/revision/{revision}/children", getRevisionChildren)
+	router("GET", "/revision/{revision}/children/{child}", getRevisionChild)
+
 	router("POST", "/revision/{revision}/rating", api.AuthHandler(postRevisionRating, true))
 	router("DELETE", "/revision/{revision}/rating", api.AuthHandler(deleteRevisionRating, true))
 }
@@ -73,6 +85,9 @@ type PostRevisionResponse struct {
 
 	Thumbnail string `json:"thumbnail"`
 	Render    string `json:"render"`
+
+	// URLs of all render variants by variant name
+	Renders map[string]string `json:"renders"`
 }
 
 /*
@@ -96,16 +111,18 @@ func postRevision(u *db.User, r *http.Request) (interface{}, *utils.ErrorRespons
 		return nil, &utils.Error_no_access
 	}
 
-	i := blueprint.IncrementAndGetRevision()
-
-	bpVersion, _ := strconv.Atoi(request.Blueprint[0:1])
-
 	sha265 := utils.SHA265(request.Blueprint)
 
-	if db.FindRevisionByChecksum(sha265) != nil {
+	if db.FindBlueprintRevisionByChecksum(blueprint.ID, sha265) != nil {
 		return nil, &utils.Error_blueprint_string_already_exists
 	}
 
+	i := blueprint.IncrementAndGetRevision()
+
+	bpVersion, _ := strconv.Atoi(request.Blueprint[0:1])
+
+	container, _ := bp.Decode(request.Blueprint)
+
 	revision := &db.Revision{
 		BlueprintID:       request.BlueprintId,
 		Revision:          i,
@@ -114,18 +131,15 @@ func postRevision(u *db.User, r *http.Request) (interface{}, *utils.ErrorRespons
 		BlueprintChecksum: sha265,
 	}
 
-	revision.Save()
-
-	storage.SaveRevision(revision.ID, request.Blueprint)
-	go storage.RenderAndSaveAndUpdateBlueprint(request.Blueprint, revision)
-
-	baseRenderStorageURL := storage.PublicURL + "/" + storage.BlueprintRenderBucket + "/" + revision.BlueprintChecksum
+	saveRevision(revision, container, request.Blueprint)
+	revisionsChanged(blueprint.ID)
 
 	return PostRevisionResponse{
 		RevisionId: revision.ID,
 		Revision:   revision.Revision,
-		Thumbnail:  baseRenderStorageURL + "-thumbnail.png",
-		Render:     baseRenderStorageURL + ".png",
+		Thumbnail:  render.URL(revision.BlueprintChecksum, "thumbnail"),
+		Render:     render.URL(revision.BlueprintChecksum, "render"),
+		Renders:    render.URLs(revision.BlueprintChecksum),
 	}, nil
 }
 
@@ -160,8 +174,11 @@ func updateRevision(u *db.User, r *http.Request) (interface{}, *utils.ErrorRespo
 		return nil, e
 	}
 
-	revision.Changes = request.Changes
-	revision.Save()
+	if revision.Changes != request.Changes {
+		revision.Changes = request.Changes
+		revision.Save()
+		db.UpdateBlueprintSearch(revision.BlueprintID)
+	}
 
 	return nil, nil
 }
@@ -186,10 +203,12 @@ func deleteRevision(u *db.User, r *http.Request) (interface{}, *utils.ErrorRespo
 		return nil, e
 	}
 
-	revision.Delete()
+	removeRevision(revision)
 
 	if blueprint.CountRevisions() == 0 {
 		blueprint.Delete()
+	} else {
+		revisionsChanged(blueprint.ID)
 	}
 
 	return nil, nil
@@ -210,13 +229,121 @@ func getRevisionComments(r *http.Request) (interface{}, *utils.ErrorResponse) {
 	}
 
 	comments := revision.GetComments()
-	reComment := reCommentData(comments)
+	reComment := reCommentTree(comments)
 
 	return GetRevisionCommentsResponse{
 		Comments: reComment,
 	}, nil
 }
 
+const (
+	RenderStatusPending   = "pending"
+	RenderStatusRendering = "rendering"
+	RenderStatusReady     = "ready"
+	RenderStatusFailed    = "failed"
+)
+
+// RevisionRender is the render progress of a revision, images are only
+// linked once they are ready
+type RevisionRender struct {
+	Status     string     `json:"render-status"`
+	RenderedAt *time.Time `json:"rendered-at,omitempty"`
+	Error      string     `json:"render-error,omitempty"`
+	Thumbnail  string     `json:"thumbnail,omitempty"`
+	Render     string     `json:"render,omitempty"`
+
+	// URLs of all render variants by variant name
+	Renders map[string]string `json:"renders,omitempty"`
+}
+
+type RevisionMaterials struct {
+	Entities map[string]int     `json:"entities"`
+	Tiles    map[string]int     `json:"tiles"`
+	Items    map[string]int     `json:"items"`
+	Raw      map[string]float64 `json:"raw"`
+}
+
+/*
+Get the bill of materials of a revision
+*/
+func getRevisionMaterials(r *http.Request) (interface{}, *utils.ErrorResponse) {
+	revision, e := parseRevision(r)
+
+	if e != nil {
+		return nil, e
+	}
+
+	return revisionMaterials(revision)
+}
+
+type RevisionChild struct {
+	Index           int    `json:"index"`
+	Kind            string `json:"kind"`
+	Label           string `json:"label"`
+	Description     string `json:"description"`
+	BlueprintString string `json:"blueprint-string"`
+	Thumbnail       string `json:"thumbnail,omitempty"`
+	Render          string `json:"render,omitempty"`
+
+	Renders map[string]string `json:"renders,omitempty"`
+}
+
+type GetRevisionChildrenResponse struct {
+	Children []*RevisionChild `json:"children"`
+}
+
+/*
+Get all children of a blueprint book revision
+*/
+func getRevisionChildren(r *http.Request) (interface{}, *utils.ErrorResponse) {
+	revision, e := parseRevision(r)
+
+	if e != nil {
+		return nil, e
+	}
+
+	children, e := revisionChildren(revision)
+
+	if e != nil {
+		return nil, e
+	}
+
+	return GetRevisionChildrenResponse{
+		Children: children,
+	}, nil
+}
+
+/*
+Get a specific child of a blueprint book revision
+*/
+func getRevisionChild(r *http.Request) (interface{}, *utils.ErrorResponse) {
+	revision, e := parseRevision(r)
+
+	if e != nil {
+		return nil, e
+	}
+
+	index, err := strconv.Atoi(mux.Vars(r)["child"])
+
+	if err != nil {
+		return nil, &utils.Error_revision_child_not_found
+	}
+
+	children, e := revisionChildren(revision)
+
+	if e != nil {
+		return nil, e
+	}
+
+	for _, child := range children {
+		if child.Index == index {
+			return child, nil
+		}
+	}
+
+	return nil, &utils.Error_revision_child_not_found
+}
+
 type PostRevisionRating struct {
 	ThumbsUp bool `json:"thumbs-up"`
 }
@@ -240,7 +367,10 @@ func postRevisionRating(u *db.User, r *http.Request) (interface{}, *utils.ErrorR
 	rating.RevisionID = revision.ID
 	rating.ThumbsUp = request.ThumbsUp
 	rating.DeletedAt = nil
-	rating.Save()
+
+	if err := rating.Save(); err != nil {
+		return nil, &utils.Error_internal_error
+	}
 
 	return nil, nil
 }
@@ -264,7 +394,9 @@ func deleteRevisionRating(u *db.User, r *http.Request) (interface{}, *utils.Erro
 		return nil, &utils.Error_rating_not_found
 	}
 
-	rating.Delete()
+	if err := rating.Delete(); err != nil {
+		return nil, &utils.Error_internal_error
+	}
 
 	return nil, nil
 }
@@ -285,11 +417,244 @@ func parseRevision(r *http.Request) (*db.Revision, *utils.ErrorResponse) {
 	return revision, nil
 }
 
+func saveRevision(revision *db.Revision, container *bp.Container, blueprintString string) {
+	revision.Kind = container.Kind()
+	revision.Save()
+
+	// Revisions that fail to index are picked up by cmd/reindex
+	if err := db.IndexRevision(revision, container); err != nil {
+		fmt.Printf("[Index] Revision %d failed: %v\n", revision.ID, err)
+	}
+
+	storage.SaveRevision(revision, blueprintString)
+	render.Queue(revision.ID)
+}
+
+// revisionsChanged recomputes the search vector and score of the blueprint
+// after a revision was added or removed
+func revisionsChanged(blueprintID uint) {
+	db.UpdateBlueprintSearch(blueprintID)
+
+	// Scores that fail to update are fixed by cmd/reindex -scores
+	if err := db.UpdateBlueprintScore(blueprintID); err != nil {
+		fmt.Printf("[Score] Blueprint %d failed: %v\n", blueprintID, err)
+	}
+}
+
+var renderStatuses = map[string]string{
+	db.RenderJobQueued:  RenderStatusPending,
+	db.RenderJobRunning: RenderStatusRendering,
+	db.RenderJobDone:    RenderStatusReady,
+	db.RenderJobFailed:  RenderStatusFailed,
+}
+
+// revisionRender of the revision, revisions without a job have never been
+// queued and count as pending
+func revisionRender(revision *db.Revision) RevisionRender {
+	return jobRender(revision, revision.GetRenderJob())
+}
+
+// revisionRenders of several revisions by revision id, loading their jobs at
+// once
+func revisionRenders(revisions []*db.Revision) map[uint]RevisionRender {
+	ids := make([]uint, 0, len(revisions))
+
+	for _, revision := range revisions {
+		if revision != nil {
+			ids = append(ids, revision.ID)
+		}
+	}
+
+	jobs := db.GetRenderJobs(ids)
+	states := make(map[uint]RevisionRender, len(ids))
+
+	for _, revision := range revisions {
+		if revision != nil {
+			states[revision.ID] = jobRender(revision, jobs[revision.ID])
+		}
+	}
+
+	return states
+}
+
+// jobRender is the render progress of the revision according to its job
+func jobRender(revision *db.Revision, job *db.RenderJob) RevisionRender {
+	if job == nil {
+		return RevisionRender{
+			Status: RenderStatusPending,
+		}
+	}
+
+	state := RevisionRender{
+		Status: renderStatuses[job.Status],
+		Error:  job.Error,
+	}
+
+	if state.Status != RenderStatusReady {
+		return state
+	}
+
+	state.RenderedAt = job.FinishedAt
+
+	// Planners are never rendered
+	if revision.Kind == bp.KindBlueprint || revision.Kind == bp.KindBlueprintBook {
+		state.Thumbnail = render.URL(revision.BlueprintChecksum, "thumbnail")
+		state.Render = render.URL(revision.BlueprintChecksum, "render")
+		state.Renders = render.URLs(revision.BlueprintChecksum)
+	}
+
+	return state
+}
+
+func removeRevision(revision *db.Revision) {
+	revision.Delete()
+	storage.DeleteRevision(revision)
+}
+
+// Longest a client may wait for a render to finish
+const maxRenderWait = 60 * time.Second
+
+// Polling for a finished render starts at the first interval and backs off
+// up to the last one
+const (
+	renderPollMin = 1 * time.Second
+	renderPollMax = 8 * time.Second
+)
+
+// Requests waiting for a render at once, further requests are answered right
+// away
+var renderWaiters = make(chan struct{}, 64)
+
+/*
+Get the render status of a revision, waits up to wait seconds for a pending render to finish
+*/
+func getRevisionRender(r *http.Request) (interface{}, *utils.ErrorResponse) {
+	revision, e := parseRevision(r)
+
+	if e != nil {
+		return nil, e
+	}
+
+	state := revisionRender(revision)
+
+	wait, _ := strconv.Atoi(r.URL.Query().Get("wait"))
+	deadline := time.Now().Add(time.Duration(utils.MinMax(0, wait, int(maxRenderWait/time.Second))) * time.Second)
+
+	if state.Status == RenderStatusReady || state.Status == RenderStatusFailed || !time.Now().Before(deadline) {
+		return state, nil
+	}
+
+	select {
+	case renderWaiters <- struct{}{}:
+		defer func() { <-renderWaiters }()
+	default:
+		return state, nil
+	}
+
+	poll := renderPollMin
+
+	for {
+		remaining := time.Until(deadline)
+
+		if remaining <= 0 {
+			return state, nil
+		}
+
+		if poll > remaining {
+			poll = remaining
+		}
+
+		select {
+		case <-r.Context().Done():
+			return state, nil
+		case <-time.After(poll):
+		}
+
+		state = revisionRender(revision)
+
+		if state.Status == RenderStatusReady || state.Status == RenderStatusFailed {
+			return state, nil
+		}
+
+		if poll *= 2; poll > renderPollMax {
+			poll = renderPollMax
+		}
+	}
+}
+
+func revisionMaterials(revision *db.Revision) (*RevisionMaterials, *utils.ErrorResponse) {
+	container := storage.GetRevisionBlueprint(revision)
+
+	if container == nil {
+		return nil, &utils.Error_internal_error
+	}
+
+	materials := container.Materials()
+
+	return &RevisionMaterials{
+		Entities: materials.Entities,
+		Tiles:    materials.Tiles,
+		Items:    materials.Items,
+		Raw:      materials.Raw,
+	}, nil
+}
+
+func revisionChildren(revision *db.Revision) ([]*RevisionChild, *utils.ErrorResponse) {
+	if revision.Kind != bp.KindBlueprintBook {
+		return nil, &utils.Error_revision_not_book
+	}
+
+	container := storage.GetRevisionBlueprint(revision)
+
+	if container == nil || container.BlueprintBook == nil {
+		return nil, &utils.Error_internal_error
+	}
+
+	entries := container.BlueprintBook.Blueprints
+	children := make([]*RevisionChild, 0, len(entries))
+
+	// Children are rendered along with the book
+	rendered := revisionRender(revision).Status == RenderStatusReady
+
+	for _, entry := range entries {
+		childString, err := bp.Encode(&entry.Container)
+
+		if err != nil {
+			return nil, &utils.Error_internal_error
+		}
+
+		child := &RevisionChild{
+			Index:           entry.Index,
+			Kind:            entry.Kind(),
+			Label:           entry.Label(),
+			Description:     entry.Description(),
+			BlueprintString: childString,
+		}
+
+		if rendered && entry.Renderable() {
+			checksum := utils.SHA265(childString)
+			child.Thumbnail = render.URL(checksum, "thumbnail")
+			child.Render = render.URL(checksum, "render")
+			child.Renders = render.URLs(checksum)
+		}
+
+		children = append(children, child)
+	}
+
+	return children, nil
+}
+
 func revisionToJSON(authUser *db.User, revision *db.Revision, getComments bool) (*Revision, *utils.ErrorResponse) {
 	if revision == nil || revision.DeletedAt != nil {
 		return nil, &utils.Error_revision_not_found
 	}
 
+	return revisionRenderToJSON(authUser, revision, getComments, revisionRender(revision))
+}
+
+// revisionRenderToJSON is revisionToJSON with the render progress already
+// loaded
+func revisionRenderToJSON(authUser *db.User, revision *db.Revision, getComments bool, state RevisionRender) (*Revision, *utils.ErrorResponse) {
 	ratings := revision.GetRatings()
 	thumbsUp, thumbsDown, userVote := 0, 0, 0
 
@@ -313,11 +678,9 @@ func revisionToJSON(authUser *db.User, revision *db.Revision, getComments bool)
 
 	if getComments {
 		comments := revision.GetComments()
-		reComment = reCommentData(comments)
+		reComment = reCommentTree(comments)
 	}
 
-	baseRenderStorageURL := storage.PublicURL + "/" + storage.BlueprintRenderBucket + "/" + revision.BlueprintChecksum
-
 	return &Revision{
 		Id:          revision.ID,
 		Revision:    revision.Revision,
@@ -325,26 +688,29 @@ func revisionToJSON(authUser *db.User, revision *db.Revision, getComments bool)
 		CreatedAt:   revision.CreatedAt,
 		UpdatedAt:   revision.UpdatedAt,
 		BlueprintID: revision.BlueprintID,
-		Blueprint:   storage.PublicURL + "/" + storage.BlueprintStringBucket + "/" + storage.RevisionToString(revision.ID),
+		Blueprint:   storage.URL(storage.BlueprintStringBucket, revision.BlueprintChecksum),
 		ThumbsUp:    thumbsUp,
 		ThumbsDown:  thumbsDown,
 		UserVote:    userVote,
 		Comments:    reComment,
 		Version:     revision.BlueprintVersion,
-		Thumbnail:   baseRenderStorageURL + "-thumbnail.png",
-		Render:      baseRenderStorageURL + ".png",
+		Kind:        revision.Kind,
+		Checksum:    revision.BlueprintChecksum,
+
+		RevisionRender: state,
 	}, nil
 }
 
 func reRevisionData(authUser *db.User, revisions []*db.Revision, getComments bool) ([]*Revision, *utils.ErrorResponse) {
 	reRevision := make([]*Revision, len(revisions))
+	states := revisionRenders(revisions)
 
 	for i, revision := range revisions {
 		if revision.DeletedAt != nil {
 			continue
 		}
 
-		rev, err := revisionToJSON(authUser, revision, getComments)
+		rev, err := revisionRenderToJSON(authUser, revision, getComments, states[revision.ID])
 
 		if err != nil {
 			return nil, err
@@ -37,6 +37,7 @@ func RegisterUserRoutes(router api.RegisterRoute) {
 	router("GET", "/user/self", api.AuthHandler(getUserSelf, false))
 	router("PUT", "/user/self", api.AuthHandler(putUserSelf, false))
 	router("GET", "/user/self/blueprints", api.AuthHandler(getUserSelfBlueprints, false))
+	router("GET", "/user/self/favorites", api.AuthHandler(getUserSelfFavorites, false))
 
 	router("GET", "/user/{user}", getUser)
 	router("GET", "/user/{user}/blueprints", getUserBlueprints)
@@ -199,6 +200,15 @@ func getUserSelfBlueprints(user *db.User, r *http.Request) (interface{}, *utils.
 	return getBlueprintsUser(user)
 }
 
+/*
+Get the favorite blueprints of the authenticated user, most recently favorited first
+*/
+func getUserSelfFavorites(user *db.User, r *http.Request) (interface{}, *utils.ErrorResponse) {
+	return UserBlueprintResponse{
+		Blueprints: reBlueprintData(user.GetFavoriteBlueprints()),
+	}, nil
+}
+
 func getBlueprintsUser(user *db.User) (interface{}, *utils.ErrorResponse) {
 	blueprints := user.GetUserBlueprints()
 	reBlueprint := reBlueprintData(blueprints)
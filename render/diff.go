@@ -0,0 +1,172 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+
+	"github.com/BlooperDB/API/blueprint"
+	"github.com/BlooperDB/API/storage"
+)
+
+var (
+	diffUnchanged = color.RGBA{0x55, 0x56, 0x5a, 0xff}
+	diffAdded     = color.RGBA{0x4c, 0xc3, 0x4f, 0xff}
+	diffRemoved   = color.RGBA{0xe0, 0x4a, 0x3c, 0xff}
+	diffChanged   = color.RGBA{0xf0, 0xc8, 0x2a, 0xff}
+)
+
+// Diff images are cached below this prefix by the checksums of both strings
+const diffPrefix = "diff/"
+
+// DiffImage draws the new blueprint with added entities in green, changed
+// and moved ones in yellow and the removed ones of the old blueprint in red.
+// Returns ErrTooLarge if both together span more than schematicMaxTiles.
+func DiffImage(from *blueprint.Blueprint, to *blueprint.Blueprint) (image.Image, error) {
+	if from == nil {
+		from = &blueprint.Blueprint{}
+	}
+
+	if to == nil {
+		to = &blueprint.Blueprint{}
+	}
+
+	bounds := unionBounds(from, to)
+
+	if err := checkBounds(bounds); err != nil {
+		return nil, err
+	}
+
+	diff := blueprint.DiffBlueprints(from, to)
+	canvas := newCanvas(bounds)
+
+	for _, tile := range to.Tiles {
+		canvas.tile(tile, tileColor(tile.Name))
+	}
+
+	fromEntities := indexEntities(from)
+
+	highlighted := make(map[string]color.RGBA)
+
+	for _, entity := range diff.Added {
+		highlighted[entityKey(entity.Name, entity.Position)] = diffAdded
+	}
+
+	for _, entity := range diff.Changed {
+		highlighted[entityKey(entity.Name, entity.Position)] = diffChanged
+	}
+
+	for _, move := range diff.Moved {
+		highlighted[entityKey(move.Name, move.To)] = diffChanged
+	}
+
+	// Highlights are drawn last to stay on top of unchanged neighbours
+	for _, entity := range to.Entities {
+		if _, ok := highlighted[entityKey(entity.Name, entity.Position)]; !ok {
+			canvas.entity(entity, diffUnchanged)
+		}
+	}
+
+	for _, entity := range diff.Removed {
+		if removed, ok := fromEntities[entityKey(entity.Name, entity.Position)]; ok {
+			canvas.entity(removed, diffRemoved)
+		}
+	}
+
+	for _, entity := range to.Entities {
+		if fill, ok := highlighted[entityKey(entity.Name, entity.Position)]; ok {
+			canvas.entity(entity, fill)
+		}
+	}
+
+	return canvas.img, nil
+}
+
+// Diff returns the encoded diff image of two blueprint strings, child is the
+// index of the compared book entries or nil. Blueprints too large to draw
+// give ErrTooLarge.
+func Diff(fromChecksum string, toChecksum string, child *int, from *blueprint.Blueprint, to *blueprint.Blueprint, format string) ([]byte, error) {
+	contentType, ok := ImageFormats[format]
+
+	if !ok {
+		return nil, ErrUnsupportedFormat
+	}
+
+	key := diffPrefix + fromChecksum + "-" + toChecksum
+
+	if child != nil {
+		key += "-" + strconv.Itoa(*child)
+	}
+
+	key += "." + format
+
+	if data, err := storage.GetRender(key); err == nil {
+		return data, nil
+	} else if err != storage.ErrNotFound {
+		return nil, err
+	}
+
+	img, err := DiffImage(from, to)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	if err := Encode(&buf, img, contentType); err != nil {
+		return nil, err
+	}
+
+	// The image is still served if it can't be cached
+	if err := storage.SaveRender(key, bytes.NewReader(buf.Bytes()), contentType); err != nil {
+		fmt.Printf("[Render] Unable to cache %s: %v\n", key, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// unionBounds covers all blueprints that are not empty
+func unionBounds(blueprints ...*blueprint.Blueprint) blueprint.Bounds {
+	bounds := blueprint.Bounds{
+		MinX: math.Inf(1),
+		MinY: math.Inf(1),
+		MaxX: math.Inf(-1),
+		MaxY: math.Inf(-1),
+	}
+
+	for _, bp := range blueprints {
+		if len(bp.Entities) == 0 && len(bp.Tiles) == 0 {
+			continue
+		}
+
+		b := bp.Bounds()
+		bounds.MinX = math.Min(bounds.MinX, b.MinX)
+		bounds.MinY = math.Min(bounds.MinY, b.MinY)
+		bounds.MaxX = math.Max(bounds.MaxX, b.MaxX)
+		bounds.MaxY = math.Max(bounds.MaxY, b.MaxY)
+	}
+
+	if math.IsInf(bounds.MinX, 1) {
+		return blueprint.Bounds{}
+	}
+
+	return bounds
+}
+
+func indexEntities(bp *blueprint.Blueprint) map[string]blueprint.Entity {
+	entities := make(map[string]blueprint.Entity, len(bp.Entities))
+
+	for _, entity := range bp.Entities {
+		entities[entityKey(entity.Name, entity.Position)] = entity
+	}
+
+	return entities
+}
+
+func entityKey(name string, position blueprint.Position) string {
+	return name + "@" + strconv.FormatFloat(position.X, 'f', -1, 64) + "," + strconv.FormatFloat(position.Y, 'f', -1, 64)
+}
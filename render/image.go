@@ -0,0 +1,122 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"strconv"
+
+	"github.com/BlooperDB/API/storage"
+
+	// Variants may be stored as webp
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnknownVariant    = errors.New("unknown render variant")
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrTooLarge          = errors.New("blueprint too large to draw")
+)
+
+// MaxImageSize is the largest width or height renders are resized to
+const MaxImageSize = 2048
+
+// Requested sizes are rounded up to one of these so that only a few resized
+// images are cached per render
+var imageSizes = []int{32, 64, 128, 256, 512, 1024, MaxImageSize}
+
+// Content types of the formats renders can be served in
+var ImageFormats = map[string]string{
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"webp": "image/webp",
+}
+
+// Resized renders are cached below this prefix, followed by the checksum
+const resizedPrefix = "resized/"
+
+// Image returns a render variant scaled down to fit into width x height and
+// encoded in the format, zero sizes are unlimited and an empty variant is the
+// first one. Sizes are rounded up to the next of the image sizes. Results are
+// cached in storage until the string is rendered again.
+func Image(checksum string, variantName string, width int, height int, format string) ([]byte, error) {
+	if variantName == "" && len(Variants) > 0 {
+		variantName = Variants[0].Name
+	}
+
+	variant := GetVariant(variantName)
+
+	if variant == nil {
+		return nil, ErrUnknownVariant
+	}
+
+	contentType, ok := ImageFormats[format]
+
+	if !ok {
+		return nil, ErrUnsupportedFormat
+	}
+
+	width, height = snapSize(width), snapSize(height)
+
+	key := resizedPrefix + checksum + "/" + variant.Name + "-" + strconv.Itoa(width) + "x" + strconv.Itoa(height) + "." + format
+
+	if data, err := storage.GetRender(key); err == nil {
+		return data, nil
+	} else if err != storage.ErrNotFound {
+		return nil, err
+	}
+
+	source, err := storage.GetRender(checksum + variant.Suffix)
+
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(source))
+
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	fitWidth, fitHeight := Fit(bounds.Dx(), bounds.Dy(), width, height)
+
+	if fitWidth != bounds.Dx() || fitHeight != bounds.Dy() {
+		img = Resize(img, fitWidth, fitHeight)
+	}
+
+	var buf bytes.Buffer
+
+	if err := Encode(&buf, img, contentType); err != nil {
+		return nil, err
+	}
+
+	// The image is still served if it can't be cached
+	if err := storage.SaveRender(key, bytes.NewReader(buf.Bytes()), contentType); err != nil {
+		fmt.Printf("[Render] Unable to cache %s: %v\n", key, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// snapSize rounds a size up to the next of the image sizes, zero stays
+// unlimited
+func snapSize(size int) int {
+	if size <= 0 {
+		return 0
+	}
+
+	for _, s := range imageSizes {
+		if size <= s {
+			return s
+		}
+	}
+
+	return MaxImageSize
+}
+
+// clearResized drops the cached resized images of a blueprint string
+func clearResized(checksum string) error {
+	return storage.DeleteRenders(resizedPrefix + checksum + "/")
+}
@@ -0,0 +1,26 @@
+package render
+
+import "testing"
+
+func TestSnapSize(t *testing.T) {
+	tests := []struct {
+		size int
+		want int
+	}{
+		{0, 0},
+		{1, 32},
+		{32, 32},
+		{33, 64},
+		{200, 256},
+		{1024, 1024},
+		{1025, 2048},
+		{MaxImageSize, MaxImageSize},
+		{MaxImageSize + 1, MaxImageSize},
+	}
+
+	for _, test := range tests {
+		if got := snapSize(test.size); got != test.want {
+			t.Errorf("snapSize(%d): got %d, want %d", test.size, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/BlooperDB/API/blueprint"
+	"github.com/BlooperDB/API/db"
+	"github.com/BlooperDB/API/storage"
+	"github.com/BlooperDB/API/utils"
+)
+
+// Revision renders the blueprint string of the revision and, for books, the
+// strings of all their renderable entries
+func Revision(revision *db.Revision) error {
+	blueprintString := storage.GetRevision(revision)
+
+	if blueprintString == nil {
+		return errors.New("blueprint string not found")
+	}
+
+	container, err := blueprint.Decode(*blueprintString)
+
+	if err != nil {
+		return err
+	}
+
+	if !container.Renderable() {
+		return nil
+	}
+
+	if err := Blueprint(*blueprintString); err != nil {
+		return err
+	}
+
+	if container.BlueprintBook != nil {
+		return bookChildren(container.BlueprintBook)
+	}
+
+	return nil
+}
+
+// bookChildren renders the renderable entries of the book, entries of nested
+// books included
+func bookChildren(book *blueprint.Book) error {
+	for _, entry := range book.Blueprints {
+		if !entry.Renderable() {
+			continue
+		}
+
+		childString, err := blueprint.Encode(&entry.Container)
+
+		if err != nil {
+			return err
+		}
+
+		if err := Blueprint(childString); err != nil {
+			return err
+		}
+
+		if entry.BlueprintBook != nil {
+			if err := bookChildren(entry.BlueprintBook); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Blueprint renders every variant of a blueprint string, images are stored
+// by its checksum and resized copies of earlier renders are dropped
+func Blueprint(blueprintString string) error {
+	sha265 := utils.SHA265(blueprintString)
+
+	for _, variant := range Variants {
+		if err := renderAndSave(blueprintString, variant, sha265+variant.Suffix); err != nil {
+			return errors.New(variant.Name + ": " + err.Error())
+		}
+	}
+
+	return clearResized(sha265)
+}
+
+func renderAndSave(blueprintString string, variant Variant, key string) error {
+	data, err := renderVariant(blueprintString, variant)
+
+	if err != nil {
+		return err
+	}
+
+	return storage.SaveRender(key, bytes.NewReader(data), variant.ContentType)
+}
+
+// renderVariant renders with the external renderer, falling back to the
+// built-in schematic renderer when it is not configured or unreachable
+func renderVariant(blueprintString string, variant Variant) ([]byte, error) {
+	rendererURL := os.Getenv("RENDERER_URL")
+
+	if rendererURL == "" {
+		return renderFallback(blueprintString, variant)
+	}
+
+	resp, err := http.Post(rendererURL+"/"+variant.Query, "text/plain", strings.NewReader(blueprintString))
+
+	if err != nil {
+		fmt.Printf("[Render] Renderer unreachable, drawing schematic instead: %v\n", err)
+		return renderFallback(blueprintString, variant)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("renderer responded with " + resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return fitVariant(data, variant)
+}
+
+func renderFallback(blueprintString string, variant Variant) ([]byte, error) {
+	container, err := blueprint.Decode(blueprintString)
+
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := fallbackVariant(container, variant)
+
+	if err != nil {
+		return nil, err
+	}
+
+	img, _ = fitImage(img, variant)
+
+	var buf bytes.Buffer
+
+	if err := Encode(&buf, img, variant.ContentType); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// fitVariant converts a render to the content type of the variant, scaled
+// down to its maximum size. Renders already in that format and size and
+// variants of formats that cannot be encoded are kept as they are.
+func fitVariant(data []byte, variant Variant) ([]byte, error) {
+	if !encodable(variant.ContentType) {
+		return data, nil
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+
+	if err != nil {
+		return nil, err
+	}
+
+	img, resized := fitImage(img, variant)
+
+	if !resized && ImageFormats[format] == variant.ContentType {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+
+	if err := Encode(&buf, img, variant.ContentType); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// fitImage scales the image down to the maximum size of the variant, reporting
+// whether it was resized
+func fitImage(img image.Image, variant Variant) (image.Image, bool) {
+	bounds := img.Bounds()
+	width, height := Fit(bounds.Dx(), bounds.Dy(), variant.MaxWidth, variant.MaxHeight)
+
+	if width == bounds.Dx() && height == bounds.Dy() {
+		return img, false
+	}
+
+	return Resize(img, width, height), true
+}
+
+// Encode writes the image in the format of the content type
+func Encode(w io.Writer, img image.Image, contentType string) error {
+	switch contentType {
+	case "image/png":
+		return png.Encode(w, img)
+	case "image/jpeg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	case "image/webp":
+		return EncodeWebP(w, img)
+	}
+
+	return errors.New("unable to encode " + contentType)
+}
@@ -0,0 +1,83 @@
+package render
+
+import (
+	"image"
+	"image/color"
+)
+
+// Fit returns the size of a width x height image scaled down to fit into
+// maxWidth x maxHeight keeping its aspect ratio, zero limits are ignored
+func Fit(width int, height int, maxWidth int, maxHeight int) (int, int) {
+	scale := 1.0
+
+	if maxWidth > 0 && width > maxWidth {
+		scale = float64(maxWidth) / float64(width)
+	}
+
+	if maxHeight > 0 && float64(height)*scale > float64(maxHeight) {
+		scale = float64(maxHeight) / float64(height)
+	}
+
+	w, h := int(float64(width)*scale+0.5), int(float64(height)*scale+0.5)
+
+	if w < 1 {
+		w = 1
+	}
+
+	if h < 1 {
+		h = 1
+	}
+
+	return w, h
+}
+
+// Resize scales the image to width x height by averaging all source pixels
+// covered by each destination pixel, which is good enough for downscaling
+func Resize(src image.Image, width int, height int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	bounds := src.Bounds()
+
+	scaleX := float64(bounds.Dx()) / float64(width)
+	scaleY := float64(bounds.Dy()) / float64(height)
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + int(float64(y)*scaleY)
+		y1 := bounds.Min.Y + int(float64(y+1)*scaleY)
+
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + int(float64(x)*scaleX)
+			x1 := bounds.Min.X + int(float64(x+1)*scaleX)
+
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					// Premultiplied so transparent pixels do not bleed
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,105 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		width, height       int
+		maxWidth, maxHeight int
+		wantW, wantH        int
+	}{
+		{100, 50, 0, 0, 100, 50},
+		{100, 50, 200, 200, 100, 50},
+		{100, 50, 50, 0, 50, 25},
+		{100, 50, 0, 10, 20, 10},
+		{100, 50, 50, 10, 20, 10},
+		{50, 100, 25, 100, 25, 50},
+		{3, 1, 2, 2, 2, 1},
+		{1000, 1, 10, 10, 10, 1},
+		{1, 1000, 10, 10, 1, 10},
+	}
+
+	for _, test := range tests {
+		w, h := Fit(test.width, test.height, test.maxWidth, test.maxHeight)
+
+		if w != test.wantW || h != test.wantH {
+			t.Errorf("Fit(%d, %d, %d, %d): got %dx%d, want %dx%d",
+				test.width, test.height, test.maxWidth, test.maxHeight, w, h, test.wantW, test.wantH)
+		}
+	}
+}
+
+func TestResize(t *testing.T) {
+	solid := image.NewNRGBA(image.Rect(0, 0, 7, 5))
+	checkers := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	half := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	offset := image.NewNRGBA(image.Rect(10, 10, 12, 12))
+
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 7; x++ {
+			solid.Set(x, y, color.NRGBA{200, 100, 50, 255})
+		}
+	}
+
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if (x+y)%2 == 0 {
+				checkers.Set(x, y, color.White)
+			} else {
+				checkers.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	half.Set(0, 0, color.NRGBA{255, 0, 0, 255})
+	half.Set(1, 0, color.Transparent)
+
+	for y := 10; y < 12; y++ {
+		for x := 10; x < 12; x++ {
+			offset.Set(x, y, color.NRGBA{0, 0, 255, 255})
+		}
+	}
+
+	tests := []struct {
+		name          string
+		src           image.Image
+		width, height int
+		want          color.NRGBA
+	}{
+		{"solid down", solid, 3, 2, color.NRGBA{200, 100, 50, 255}},
+		{"solid up", solid, 14, 10, color.NRGBA{200, 100, 50, 255}},
+		{"checkers", checkers, 2, 2, color.NRGBA{127, 127, 127, 255}},
+		{"transparent", half, 1, 1, color.NRGBA{255, 0, 0, 127}},
+		{"offset bounds", offset, 1, 1, color.NRGBA{0, 0, 255, 255}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dst := Resize(test.src, test.width, test.height)
+
+			if size := dst.Bounds().Size(); size.X != test.width || size.Y != test.height {
+				t.Fatalf("got %dx%d, want %dx%d", size.X, size.Y, test.width, test.height)
+			}
+
+			for y := 0; y < test.height; y++ {
+				for x := 0; x < test.width; x++ {
+					if got := dst.NRGBAAt(x, y); !closeColor(got, test.want) {
+						t.Errorf("pixel %d,%d: got %v, want %v", x, y, got, test.want)
+					}
+				}
+			}
+		})
+	}
+}
+
+// closeColor allows for rounding when converting between color models
+func closeColor(a color.NRGBA, b color.NRGBA) bool {
+	near := func(x, y uint8) bool {
+		return int(x)-int(y) <= 1 && int(y)-int(x) <= 1
+	}
+	return near(a.R, b.R) && near(a.G, b.G) && near(a.B, b.B) && near(a.A, b.A)
+}
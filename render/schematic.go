@@ -0,0 +1,297 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+
+	"github.com/BlooperDB/API/blueprint"
+	"github.com/BlooperDB/API/utils"
+)
+
+const (
+	// Pixels per tile of schematics, lowered for large blueprints so the
+	// longest side stays within schematicMaxSize
+	schematicTileSize = 16
+	schematicMaxSize  = 2048
+
+	// Blueprints spanning more tiles are not drawn
+	schematicMaxTiles = 10000
+
+	// Empty tiles around the blueprint
+	schematicPadding = 1
+
+	// Longest side of schematic thumbnails
+	schematicThumbnailSize = 256
+)
+
+var (
+	schematicBackground = color.RGBA{0x1e, 0x1f, 0x22, 0xff}
+	schematicTile       = color.RGBA{0x3a, 0x3b, 0x3e, 0xff}
+	schematicEntity     = color.RGBA{0x9e, 0x9e, 0x9e, 0xff}
+)
+
+// Entity colors by name fragment, the first match wins so more specific
+// fragments come first
+var schematicColors = []struct {
+	fragment string
+	color    color.RGBA
+}{
+	{"underground-belt", color.RGBA{0xb0, 0x8c, 0x1c, 0xff}},
+	{"splitter", color.RGBA{0xc9, 0x9a, 0x1a, 0xff}},
+	{"loader", color.RGBA{0xc9, 0x9a, 0x1a, 0xff}},
+	{"transport-belt", color.RGBA{0xe3, 0xb5, 0x2a, 0xff}},
+	{"inserter", color.RGBA{0x4f, 0x8f, 0xd6, 0xff}},
+	{"chest", color.RGBA{0x8b, 0x5e, 0x3c, 0xff}},
+	{"storage-tank", color.RGBA{0x5c, 0x7c, 0x8a, 0xff}},
+	{"pipe", color.RGBA{0x6f, 0x8f, 0x9c, 0xff}},
+	{"pump", color.RGBA{0x5c, 0x7c, 0x8a, 0xff}},
+	{"rail", color.RGBA{0x7a, 0x6a, 0x5a, 0xff}},
+	{"train-stop", color.RGBA{0xb5, 0x3a, 0x3a, 0xff}},
+	{"locomotive", color.RGBA{0xa8, 0x3a, 0x3a, 0xff}},
+	{"wagon", color.RGBA{0x8a, 0x4a, 0x4a, 0xff}},
+	{"electric-pole", color.RGBA{0x9c, 0x7a, 0x3c, 0xff}},
+	{"substation", color.RGBA{0x9c, 0x7a, 0x3c, 0xff}},
+	{"solar-panel", color.RGBA{0x2c, 0x4a, 0x7a, 0xff}},
+	{"accumulator", color.RGBA{0x6a, 0x6a, 0x8a, 0xff}},
+	{"combinator", color.RGBA{0x3c, 0xa0, 0x6a, 0xff}},
+	{"lamp", color.RGBA{0xe8, 0xe0, 0x9a, 0xff}},
+	{"wall", color.RGBA{0xb8, 0xb0, 0xa0, 0xff}},
+	{"gate", color.RGBA{0xb8, 0xb0, 0xa0, 0xff}},
+	{"turret", color.RGBA{0xc0, 0x4a, 0x2a, 0xff}},
+	{"furnace", color.RGBA{0xc2, 0x6a, 0x2a, 0xff}},
+	{"mining-drill", color.RGBA{0x7a, 0x5c, 0x3a, 0xff}},
+	{"assembling-machine", color.RGBA{0x5a, 0x8a, 0xa8, 0xff}},
+	{"chemical-plant", color.RGBA{0x4a, 0x9a, 0x8a, 0xff}},
+	{"oil-refinery", color.RGBA{0x4a, 0x7a, 0x9a, 0xff}},
+	{"beacon", color.RGBA{0x8a, 0x5a, 0xb0, 0xff}},
+	{"roboport", color.RGBA{0x8a, 0x9a, 0xb0, 0xff}},
+}
+
+// Tile colors by name fragment
+var schematicTileColors = []struct {
+	fragment string
+	color    color.RGBA
+}{
+	{"hazard", color.RGBA{0x6a, 0x5a, 0x2a, 0xff}},
+	{"refined-concrete", color.RGBA{0x4a, 0x4b, 0x4e, 0xff}},
+	{"concrete", color.RGBA{0x44, 0x45, 0x48, 0xff}},
+	{"stone-path", color.RGBA{0x4a, 0x44, 0x3c, 0xff}},
+	{"landfill", color.RGBA{0x4e, 0x44, 0x34, 0xff}},
+}
+
+// Schematic draws a top-down preview of the container where every entity is
+// a colored rectangle of its footprint. Books are drawn as their active
+// blueprint, or the first one if that can't be drawn. Returns ErrTooLarge for
+// blueprints spanning more than schematicMaxTiles.
+func Schematic(container *blueprint.Container) (image.Image, error) {
+	bp := schematicBlueprint(container)
+
+	if bp == nil {
+		bp = &blueprint.Blueprint{}
+	}
+
+	bounds := bp.Bounds()
+
+	if err := checkBounds(bounds); err != nil {
+		return nil, err
+	}
+
+	canvas := newCanvas(bounds)
+
+	for _, tile := range bp.Tiles {
+		canvas.tile(tile, tileColor(tile.Name))
+	}
+
+	for _, entity := range bp.Entities {
+		canvas.entity(entity, entityColor(entity.Name))
+	}
+
+	return canvas.img, nil
+}
+
+// checkBounds rejects bounds too large to draw
+func checkBounds(bounds blueprint.Bounds) error {
+	if bounds.Width() > schematicMaxTiles || bounds.Height() > schematicMaxTiles {
+		return ErrTooLarge
+	}
+
+	return nil
+}
+
+// canvas draws blueprints within bounds, scaled so the longest side stays
+// within schematicMaxSize. Tiles of large blueprints may be smaller than a
+// pixel, everything drawn covers at least one.
+type canvas struct {
+	img    *image.RGBA
+	bounds blueprint.Bounds
+	scale  float64
+}
+
+func newCanvas(bounds blueprint.Bounds) *canvas {
+	widthTiles := bounds.Width() + 2*schematicPadding
+	heightTiles := bounds.Height() + 2*schematicPadding
+
+	scale := float64(schematicTileSize)
+	longest := widthTiles
+
+	if heightTiles > longest {
+		longest = heightTiles
+	}
+
+	if float64(longest)*scale > schematicMaxSize {
+		scale = float64(schematicMaxSize) / float64(longest)
+	}
+
+	width := utils.MinMax(1, int(math.Ceil(float64(widthTiles)*scale)), schematicMaxSize)
+	height := utils.MinMax(1, int(math.Ceil(float64(heightTiles)*scale)), schematicMaxSize)
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: schematicBackground}, image.ZP, draw.Src)
+
+	return &canvas{
+		img:    img,
+		bounds: bounds,
+		scale:  scale,
+	}
+}
+
+// toPixels converts a position in tiles to pixels
+func (c *canvas) toPixels(x float64, y float64) (int, int) {
+	return int(math.Floor((x-c.bounds.MinX+schematicPadding)*c.scale + 0.5)),
+		int(math.Floor((y-c.bounds.MinY+schematicPadding)*c.scale + 0.5))
+}
+
+// rect converts an area in tiles to pixels, at least one pixel large
+func (c *canvas) rect(minX float64, minY float64, maxX float64, maxY float64) image.Rectangle {
+	x0, y0 := c.toPixels(minX, minY)
+	x1, y1 := c.toPixels(maxX, maxY)
+
+	if x1 <= x0 {
+		x1 = x0 + 1
+	}
+
+	if y1 <= y0 {
+		y1 = y0 + 1
+	}
+
+	return image.Rect(x0, y0, x1, y1)
+}
+
+func (c *canvas) tile(tile blueprint.Tile, fill color.RGBA) {
+	fillRect(c.img, c.rect(tile.Position.X, tile.Position.Y, tile.Position.X+1, tile.Position.Y+1), fill, false)
+}
+
+func (c *canvas) entity(entity blueprint.Entity, fill color.RGBA) {
+	size := blueprint.EntitySize(entity)
+	rect := c.rect(entity.Position.X-size.Width/2, entity.Position.Y-size.Height/2,
+		entity.Position.X+size.Width/2, entity.Position.Y+size.Height/2)
+	fillRect(c.img, rect, fill, c.scale >= 4)
+}
+
+func schematicBlueprint(container *blueprint.Container) *blueprint.Blueprint {
+	if container.Blueprint != nil {
+		return container.Blueprint
+	}
+
+	book := container.BlueprintBook
+
+	if book == nil {
+		return nil
+	}
+
+	if active := book.Child(book.ActiveIndex); active != nil {
+		if bp := schematicBlueprint(&active.Container); bp != nil {
+			return bp
+		}
+	}
+
+	for i := range book.Blueprints {
+		if bp := schematicBlueprint(&book.Blueprints[i].Container); bp != nil {
+			return bp
+		}
+	}
+
+	return nil
+}
+
+// fillRect fills the rectangle, outlined with a darker shade so neighbouring
+// entities of the same kind stay apart
+func fillRect(img *image.RGBA, rect image.Rectangle, c color.RGBA, outline bool) {
+	draw.Draw(img, rect, &image.Uniform{C: c}, image.ZP, draw.Over)
+
+	if !outline {
+		return
+	}
+
+	dark := &image.Uniform{C: color.RGBA{c.R / 2, c.G / 2, c.B / 2, c.A}}
+	draw.Draw(img, image.Rect(rect.Min.X, rect.Min.Y, rect.Max.X, rect.Min.Y+1), dark, image.ZP, draw.Src)
+	draw.Draw(img, image.Rect(rect.Min.X, rect.Max.Y-1, rect.Max.X, rect.Max.Y), dark, image.ZP, draw.Src)
+	draw.Draw(img, image.Rect(rect.Min.X, rect.Min.Y, rect.Min.X+1, rect.Max.Y), dark, image.ZP, draw.Src)
+	draw.Draw(img, image.Rect(rect.Max.X-1, rect.Min.Y, rect.Max.X, rect.Max.Y), dark, image.ZP, draw.Src)
+}
+
+func entityColor(name string) color.RGBA {
+	for _, entry := range schematicColors {
+		if strings.Contains(name, entry.fragment) {
+			return entry.color
+		}
+	}
+
+	return schematicEntity
+}
+
+func tileColor(name string) color.RGBA {
+	for _, entry := range schematicTileColors {
+		if strings.Contains(name, entry.fragment) {
+			return entry.color
+		}
+	}
+
+	return schematicTile
+}
+
+// squareImage centers the image on a square canvas of the background color
+func squareImage(img image.Image) image.Image {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+
+	if bounds.Dy() > side {
+		side = bounds.Dy()
+	}
+
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), &image.Uniform{C: schematicBackground}, image.ZP, draw.Src)
+
+	offset := image.Pt((side-bounds.Dx())/2, (side-bounds.Dy())/2)
+	draw.Draw(square, bounds.Sub(bounds.Min).Add(offset), img, bounds.Min, draw.Src)
+
+	return square
+}
+
+// fallbackVariant draws the variant with the schematic renderer, the square
+// and thumbnail options of the external renderer are read from the query
+func fallbackVariant(container *blueprint.Container, variant Variant) (image.Image, error) {
+	img, err := Schematic(container)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(variant.Query, "square") {
+		img = squareImage(img)
+	}
+
+	if strings.Contains(variant.Query, "thumb") {
+		bounds := img.Bounds()
+		width, height := Fit(bounds.Dx(), bounds.Dy(), schematicThumbnailSize, schematicThumbnailSize)
+
+		if width != bounds.Dx() || height != bounds.Dy() {
+			img = Resize(img, width, height)
+		}
+	}
+
+	return img, nil
+}
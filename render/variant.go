@@ -0,0 +1,132 @@
+package render
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/BlooperDB/API/storage"
+)
+
+// Variant of the images rendered for every blueprint string, stored as the
+// checksum of the string followed by the suffix
+type Variant struct {
+	Name string `json:"name"`
+
+	// Query string appended to RENDERER_URL, e.g. "?square"
+	Query string `json:"query"`
+
+	ContentType string `json:"content-type"`
+
+	// Larger renders are scaled down to fit, zero means unlimited
+	MaxWidth  int `json:"max-width"`
+	MaxHeight int `json:"max-height"`
+
+	Suffix string `json:"suffix"`
+}
+
+// Variants rendered for every blueprint string
+var Variants = []Variant{
+	{
+		Name:        "render",
+		ContentType: "image/png",
+		Suffix:      ".png",
+	},
+	{
+		Name:        "square",
+		Query:       "?square",
+		ContentType: "image/png",
+		Suffix:      "-square.png",
+	},
+	{
+		Name:        "thumbnail",
+		Query:       "?squarethumb",
+		ContentType: "image/png",
+		Suffix:      "-thumbnail.png",
+	},
+}
+
+// LoadVariants replaces the variants with those of a JSON file
+func LoadVariants(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var variants []Variant
+	if err := json.NewDecoder(file).Decode(&variants); err != nil {
+		return err
+	}
+
+	names := make(map[string]bool)
+	suffixes := make(map[string]bool)
+
+	for i, variant := range variants {
+		if variant.Name == "" || variant.Suffix == "" {
+			return errors.New("render variants need a name and a suffix")
+		}
+
+		if names[variant.Name] || suffixes[variant.Suffix] {
+			return errors.New("duplicate render variant " + variant.Name)
+		}
+
+		names[variant.Name] = true
+		suffixes[variant.Suffix] = true
+
+		if variant.ContentType == "" {
+			variants[i].ContentType = "image/png"
+		}
+
+		resized := variant.MaxWidth > 0 || variant.MaxHeight > 0
+
+		if resized && !encodable(variants[i].ContentType) {
+			return errors.New("render variant " + variant.Name + " can only be resized as image/png, image/jpeg or image/webp")
+		}
+	}
+
+	Variants = variants
+
+	return nil
+}
+
+func encodable(contentType string) bool {
+	for _, format := range ImageFormats {
+		if format == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+func GetVariant(name string) *Variant {
+	for i := range Variants {
+		if Variants[i].Name == name {
+			return &Variants[i]
+		}
+	}
+	return nil
+}
+
+// URL of a variant of the rendered blueprint string, empty if there is no
+// such variant
+func URL(checksum string, variant string) string {
+	v := GetVariant(variant)
+
+	if v == nil {
+		return ""
+	}
+
+	return storage.URL(storage.BlueprintRenderBucket, checksum+v.Suffix)
+}
+
+// URLs of all variants of the rendered blueprint string by variant name
+func URLs(checksum string) map[string]string {
+	urls := make(map[string]string, len(Variants))
+
+	for _, variant := range Variants {
+		urls[variant.Name] = storage.URL(storage.BlueprintRenderBucket, checksum+variant.Suffix)
+	}
+
+	return urls
+}
@@ -0,0 +1,517 @@
+package render
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"sort"
+)
+
+// Lossless WebP (VP8L) encoder, there is none in the standard library. It
+// only uses the subtract green transform and back references to the pixel to
+// the left or above, which is all renders of flat colored blueprints need.
+
+const (
+	webpMaxSize       = 1 << 14
+	webpMaxCodeLength = 15
+	webpMaxLength     = 4096
+	webpMinLength     = 3
+
+	// Distance codes of the pixel above and to the left, see the distance
+	// mapping in the VP8L specification
+	webpDistanceUp   = 1
+	webpDistanceLeft = 2
+
+	webpLengthCodes   = 24
+	webpDistanceCodes = 40
+)
+
+var webpCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// EncodeWebP writes the image as a lossless WebP
+func EncodeWebP(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width < 1 || height < 1 || width > webpMaxSize || height > webpMaxSize {
+		return errors.New("image size not supported by webp")
+	}
+
+	nrgba, ok := img.(*image.NRGBA)
+
+	if !ok || nrgba.Rect.Min != (image.Point{}) {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+	}
+
+	pixels := make([]uint32, width*height)
+	alpha := false
+
+	for i := range pixels {
+		p := nrgba.Pix[i*4 : i*4+4]
+		r, g, b, a := uint32(p[0]), uint32(p[1]), uint32(p[2]), uint32(p[3])
+
+		if a != 0xff {
+			alpha = true
+		}
+
+		// Subtract green transform
+		r = (r - g) & 0xff
+		b = (b - g) & 0xff
+
+		pixels[i] = a<<24 | r<<16 | g<<8 | b
+	}
+
+	bw := &webpBitWriter{}
+
+	// Header: signature, size, alpha hint and version
+	bw.write(0x2f, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+
+	if alpha {
+		bw.write(1, 1)
+	} else {
+		bw.write(0, 1)
+	}
+
+	bw.write(0, 3)
+
+	// Subtract green transform followed by the end of the transforms
+	bw.write(1, 1)
+	bw.write(2, 2)
+	bw.write(0, 1)
+
+	// No color cache and a single prefix code group
+	bw.write(0, 1)
+	bw.write(0, 1)
+
+	tokens := webpTokens(pixels, width)
+
+	var histograms [5][]int
+	histograms[0] = make([]int, 256+webpLengthCodes)
+	histograms[1] = make([]int, 256)
+	histograms[2] = make([]int, 256)
+	histograms[3] = make([]int, 256)
+	histograms[4] = make([]int, webpDistanceCodes)
+
+	for _, token := range tokens {
+		if token.length == 0 {
+			histograms[0][token.argb>>8&0xff]++
+			histograms[1][token.argb>>16&0xff]++
+			histograms[2][token.argb&0xff]++
+			histograms[3][token.argb>>24]++
+			continue
+		}
+
+		lengthCode, _, _ := webpPrefix(token.length)
+		distanceCode, _, _ := webpPrefix(token.distance)
+		histograms[0][256+lengthCode]++
+		histograms[4][distanceCode]++
+	}
+
+	var codes [5]webpCode
+
+	for i, histogram := range histograms {
+		codes[i] = bw.writeCode(histogram)
+	}
+
+	for _, token := range tokens {
+		if token.length == 0 {
+			codes[0].write(bw, int(token.argb>>8&0xff))
+			codes[1].write(bw, int(token.argb>>16&0xff))
+			codes[2].write(bw, int(token.argb&0xff))
+			codes[3].write(bw, int(token.argb>>24))
+			continue
+		}
+
+		lengthCode, extraBits, extra := webpPrefix(token.length)
+		codes[0].write(bw, 256+lengthCode)
+		bw.write(extra, extraBits)
+
+		distanceCode, extraBits, extra := webpPrefix(token.distance)
+		codes[4].write(bw, distanceCode)
+		bw.write(extra, extraBits)
+	}
+
+	data := bw.bytes()
+	chunkSize := len(data)
+	padding := chunkSize & 1
+
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(12+chunkSize+padding))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(chunkSize))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+
+	if padding == 1 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+
+	return nil
+}
+
+// webpToken is a literal pixel or, with a length, a back reference
+type webpToken struct {
+	argb     uint32
+	length   int
+	distance int
+}
+
+// webpTokens greedily replaces repeated pixels with back references to the
+// pixel to the left or above
+func webpTokens(pixels []uint32, width int) []webpToken {
+	tokens := make([]webpToken, 0, len(pixels)/4)
+
+	matchLength := func(i int, offset int) int {
+		if i < offset {
+			return 0
+		}
+
+		length := 0
+
+		for i+length < len(pixels) && length < webpMaxLength && pixels[i+length] == pixels[i+length-offset] {
+			length++
+		}
+
+		return length
+	}
+
+	for i := 0; i < len(pixels); {
+		length, distance := matchLength(i, 1), webpDistanceLeft
+
+		if up := matchLength(i, width); up > length {
+			length, distance = up, webpDistanceUp
+		}
+
+		if length < webpMinLength {
+			tokens = append(tokens, webpToken{argb: pixels[i]})
+			i++
+			continue
+		}
+
+		tokens = append(tokens, webpToken{length: length, distance: distance})
+		i += length
+	}
+
+	return tokens
+}
+
+// webpPrefix splits a length or distance code into its prefix symbol and
+// extra bits
+func webpPrefix(value int) (int, uint, uint32) {
+	d := value - 1
+
+	if d < 4 {
+		return d, 0, 0
+	}
+
+	highest := uint(0)
+
+	for d>>(highest+1) != 0 {
+		highest++
+	}
+
+	second := (d >> (highest - 1)) & 1
+	extraBits := highest - 1
+
+	return int(2*highest) + second, extraBits, uint32(d & (1<<extraBits - 1))
+}
+
+type webpBitWriter struct {
+	buf   []byte
+	acc   uint64
+	nBits uint
+}
+
+func (w *webpBitWriter) write(bits uint32, n uint) {
+	w.acc |= uint64(bits) << w.nBits
+	w.nBits += n
+
+	for w.nBits >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nBits -= 8
+	}
+}
+
+func (w *webpBitWriter) bytes() []byte {
+	if w.nBits > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.nBits = 0, 0
+	}
+
+	return w.buf
+}
+
+// webpCode is a canonical prefix code, codes are stored bit reversed as the
+// stream is written least significant bit first
+type webpCode struct {
+	lengths []uint
+	codes   []uint32
+}
+
+func (c webpCode) write(w *webpBitWriter, symbol int) {
+	w.write(c.codes[symbol], c.lengths[symbol])
+}
+
+// writeCode writes the prefix code for the symbol counts and returns it
+func (w *webpBitWriter) writeCode(counts []int) webpCode {
+	var used []int
+
+	for symbol, count := range counts {
+		if count > 0 {
+			used = append(used, symbol)
+		}
+	}
+
+	// Simple codes hold one or two symbols below 256, a single symbol takes
+	// no bits at all
+	if len(used) <= 2 && (len(used) == 0 || used[len(used)-1] < 256) {
+		code := webpCode{lengths: make([]uint, len(counts)), codes: make([]uint32, len(counts))}
+
+		if len(used) == 0 {
+			used = []int{0}
+		}
+
+		w.write(1, 1)
+		w.write(uint32(len(used)-1), 1)
+
+		if used[0] < 2 {
+			w.write(0, 1)
+			w.write(uint32(used[0]), 1)
+		} else {
+			w.write(1, 1)
+			w.write(uint32(used[0]), 8)
+		}
+
+		if len(used) == 2 {
+			w.write(uint32(used[1]), 8)
+			code.lengths[used[0]], code.lengths[used[1]] = 1, 1
+			code.codes[used[1]] = 1
+		}
+
+		return code
+	}
+
+	lengths := webpCodeLengths(counts, webpMaxCodeLength)
+
+	// Code lengths are themselves run length and prefix coded
+	type lengthToken struct {
+		symbol    int
+		extraBits uint
+		extra     uint32
+	}
+
+	var lengthTokens []lengthToken
+	lengthCounts := make([]int, 19)
+
+	for i := 0; i < len(lengths); {
+		if lengths[i] != 0 {
+			lengthTokens = append(lengthTokens, lengthToken{symbol: int(lengths[i])})
+			lengthCounts[lengths[i]]++
+			i++
+			continue
+		}
+
+		run := 1
+
+		for i+run < len(lengths) && lengths[i+run] == 0 && run < 138 {
+			run++
+		}
+
+		switch {
+		case run >= 11:
+			lengthTokens = append(lengthTokens, lengthToken{18, 7, uint32(run - 11)})
+			lengthCounts[18]++
+		case run >= 3:
+			lengthTokens = append(lengthTokens, lengthToken{17, 3, uint32(run - 3)})
+			lengthCounts[17]++
+		default:
+			run = 1
+			lengthTokens = append(lengthTokens, lengthToken{symbol: 0})
+			lengthCounts[0]++
+		}
+
+		i += run
+	}
+
+	lengthCode := newWebpCode(webpCodeLengths(lengthCounts, 7))
+
+	numLengths := 4
+
+	for i, symbol := range webpCodeLengthOrder {
+		if lengthCode.lengths[symbol] != 0 && i+1 > numLengths {
+			numLengths = i + 1
+		}
+	}
+
+	w.write(0, 1)
+	w.write(uint32(numLengths-4), 4)
+
+	for _, symbol := range webpCodeLengthOrder[:numLengths] {
+		w.write(uint32(lengthCode.lengths[symbol]), 3)
+	}
+
+	// Code lengths of the whole alphabet follow
+	w.write(0, 1)
+
+	for _, token := range lengthTokens {
+		lengthCode.write(w, token.symbol)
+		w.write(token.extra, token.extraBits)
+	}
+
+	return newWebpCode(lengths)
+}
+
+// newWebpCode assigns canonical codes to the code lengths
+func newWebpCode(lengths []uint) webpCode {
+	code := webpCode{lengths: lengths, codes: make([]uint32, len(lengths))}
+
+	var lengthCount [webpMaxCodeLength + 1]uint32
+
+	for _, length := range lengths {
+		lengthCount[length]++
+	}
+
+	lengthCount[0] = 0
+
+	var next [webpMaxCodeLength + 1]uint32
+	value := uint32(0)
+
+	for length := 1; length <= webpMaxCodeLength; length++ {
+		value = (value + lengthCount[length-1]) << 1
+		next[length] = value
+	}
+
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+
+		reversed := uint32(0)
+		value := next[length]
+		next[length]++
+
+		for i := uint(0); i < length; i++ {
+			reversed = reversed<<1 | value>>i&1
+		}
+
+		code.codes[symbol] = reversed
+	}
+
+	return code
+}
+
+// webpCodeLengths builds Huffman code lengths no longer than maxLength.
+// There are always at least two codes so every symbol takes a bit.
+func webpCodeLengths(counts []int, maxLength uint) []uint {
+	lengths := make([]uint, len(counts))
+
+	var used []int
+
+	for symbol, count := range counts {
+		if count > 0 {
+			used = append(used, symbol)
+		}
+	}
+
+	if len(used) < 2 {
+		other := 0
+
+		if len(used) == 1 && used[0] == 0 {
+			other = 1
+		}
+
+		lengths[other] = 1
+
+		if len(used) == 1 {
+			lengths[used[0]] = 1
+		}
+
+		return lengths
+	}
+
+	// Rare counts are raised until the tree is shallow enough
+	for minCount := 1; ; minCount *= 2 {
+		type node struct {
+			count  int
+			parent int
+		}
+
+		nodes := make([]node, 0, 2*len(used))
+
+		for _, symbol := range used {
+			count := counts[symbol]
+
+			if count < minCount {
+				count = minCount
+			}
+
+			nodes = append(nodes, node{count: count, parent: -1})
+		}
+
+		// Leaves sorted by count, internal nodes are created in order of
+		// their count so two queues replace a heap
+		order := make([]int, len(used))
+
+		for i := range order {
+			order[i] = i
+		}
+
+		sort.SliceStable(order, func(a, b int) bool {
+			return nodes[order[a]].count < nodes[order[b]].count
+		})
+
+		leaf, internal := 0, len(used)
+
+		pop := func() int {
+			if leaf < len(order) && (internal >= len(nodes) || nodes[order[leaf]].count <= nodes[internal].count) {
+				leaf++
+				return order[leaf-1]
+			}
+
+			internal++
+			return internal - 1
+		}
+
+		for i := 1; i < len(used); i++ {
+			a, b := pop(), pop()
+			nodes = append(nodes, node{count: nodes[a].count + nodes[b].count, parent: -1})
+			nodes[a].parent = len(nodes) - 1
+			nodes[b].parent = len(nodes) - 1
+		}
+
+		tooLong := false
+
+		for i, symbol := range used {
+			depth := uint(0)
+
+			for n := i; nodes[n].parent != -1; n = nodes[n].parent {
+				depth++
+			}
+
+			if depth > maxLength {
+				tooLong = true
+				break
+			}
+
+			lengths[symbol] = depth
+		}
+
+		if !tooLong {
+			return lengths
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeWebP(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name          string
+		width, height int
+		pixel         func(x, y int) color.NRGBA
+	}{
+		{"single pixel", 1, 1, func(x, y int) color.NRGBA {
+			return color.NRGBA{12, 34, 56, 255}
+		}},
+		{"solid", 64, 64, func(x, y int) color.NRGBA {
+			return color.NRGBA{40, 40, 40, 255}
+		}},
+		{"transparent", 17, 9, func(x, y int) color.NRGBA {
+			return color.NRGBA{}
+		}},
+		{"flat", 123, 77, func(x, y int) color.NRGBA {
+			// Tiles of a few colors like a rendered blueprint
+			colors := []color.NRGBA{{0, 0, 0, 255}, {200, 160, 60, 255}, {90, 90, 255, 255}, {255, 255, 255, 0}}
+			return colors[(x/8+y/5)%len(colors)]
+		}},
+		{"gradient", 300, 2, func(x, y int) color.NRGBA {
+			return color.NRGBA{uint8(x), uint8(y * 100), uint8(x * 3), uint8(255 - x/2)}
+		}},
+		{"random", 97, 53, func(x, y int) color.NRGBA {
+			return color.NRGBA{uint8(random.Intn(256)), uint8(random.Intn(256)), uint8(random.Intn(256)), 255}
+		}},
+		{"random alpha", 31, 129, func(x, y int) color.NRGBA {
+			return color.NRGBA{uint8(random.Intn(256)), uint8(random.Intn(256)), uint8(random.Intn(256)), uint8(random.Intn(256))}
+		}},
+		{"runs", 1000, 3, func(x, y int) color.NRGBA {
+			if x%250 == 0 {
+				return color.NRGBA{255, 0, 0, 255}
+			}
+			return color.NRGBA{0, 255, 0, 255}
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, test.width, test.height))
+
+			for y := 0; y < test.height; y++ {
+				for x := 0; x < test.width; x++ {
+					img.SetNRGBA(x, y, test.pixel(x, y))
+				}
+			}
+
+			var buf bytes.Buffer
+
+			if err := EncodeWebP(&buf, img); err != nil {
+				t.Fatalf("EncodeWebP: %v", err)
+			}
+
+			decoded, err := webp.Decode(&buf)
+			if err != nil {
+				t.Fatalf("webp.Decode: %v", err)
+			}
+
+			if size := decoded.Bounds().Size(); size.X != test.width || size.Y != test.height {
+				t.Fatalf("got %dx%d, want %dx%d", size.X, size.Y, test.width, test.height)
+			}
+
+			for y := 0; y < test.height; y++ {
+				for x := 0; x < test.width; x++ {
+					want := img.NRGBAAt(x, y)
+					got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+
+					// Fully transparent pixels may lose their color
+					if want.A == 0 {
+						got.R, got.G, got.B = 0, 0, 0
+						want.R, want.G, want.B = 0, 0, 0
+					}
+
+					if got != want {
+						t.Fatalf("pixel %d,%d: got %v, want %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeWebPOffsetBounds(t *testing.T) {
+	img := image.NewRGBA(image.Rect(5, 5, 8, 7))
+
+	for y := 5; y < 7; y++ {
+		for x := 5; x < 8; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 20), uint8(y * 20), 0, 255})
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if err := EncodeWebP(&buf, img); err != nil {
+		t.Fatalf("EncodeWebP: %v", err)
+	}
+
+	decoded, err := webp.Decode(&buf)
+	if err != nil {
+		t.Fatalf("webp.Decode: %v", err)
+	}
+
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			want := color.NRGBA{uint8((x + 5) * 20), uint8((y + 5) * 20), 0, 255}
+
+			if got := color.NRGBAModel.Convert(decoded.At(x, y)); got != want {
+				t.Errorf("pixel %d,%d: got %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestEncodeWebPInvalidSize(t *testing.T) {
+	for _, rect := range []image.Rectangle{
+		image.Rect(0, 0, 0, 0),
+		image.Rect(0, 0, 10, 0),
+		image.Rect(0, 0, webpMaxSize+1, 1),
+	} {
+		if err := EncodeWebP(&bytes.Buffer{}, image.NewNRGBA(rect)); err == nil {
+			t.Errorf("EncodeWebP(%v): got no error", rect)
+		}
+	}
+}
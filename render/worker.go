@@ -0,0 +1,243 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BlooperDB/API/db"
+)
+
+var (
+	// MaxAttempts before a job fails for good
+	MaxAttempts = 5
+
+	// Backoff before the first retry, doubled on every further attempt
+	Backoff = 30 * time.Second
+
+	// StaleTimeout without a heartbeat after which running jobs are assumed
+	// to be abandoned
+	StaleTimeout = 2 * time.Minute
+
+	// HeartbeatInterval at which workers mark their job as still running
+	HeartbeatInterval = 30 * time.Second
+
+	// PollInterval at which idle workers look for due jobs
+	PollInterval = 5 * time.Second
+)
+
+var wake = make(chan struct{}, 1)
+
+// Queue queues the revision for rendering and wakes up an idle worker
+func Queue(revision uint) {
+	db.QueueRenderJob(revision)
+
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+type Options struct {
+	Workers int
+
+	// Interval at which idle workers look for due jobs
+	Interval time.Duration
+
+	// Once makes workers exit as soon as no job is due
+	Once bool
+
+	// Revisions restricts the workers to the jobs of these revisions, all
+	// jobs are processed when empty
+	Revisions []uint
+}
+
+// Progress of a pool since it was started
+type Progress struct {
+	Rendered int64
+	Retrying int64
+	Failed   int64
+}
+
+// Pool of workers processing render jobs
+type Pool struct {
+	options Options
+	stop    chan struct{}
+	wg      sync.WaitGroup
+
+	rendered int64
+	retrying int64
+	failed   int64
+}
+
+// Start starts a pool of workers
+func Start(options Options) *Pool {
+	if options.Interval <= 0 {
+		options.Interval = PollInterval
+	}
+
+	p := &Pool{
+		options: options,
+		stop:    make(chan struct{}),
+	}
+
+	db.RequeueStaleRenderJobs(StaleTimeout, MaxAttempts)
+
+	for i := 0; i < options.Workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+
+	// Pools that exit once idle only requeue at the start
+	if !options.Once {
+		p.wg.Add(1)
+		go p.requeue()
+	}
+
+	return p
+}
+
+// Stop waits for all workers to finish their current job
+func (p *Pool) Stop() {
+	close(p.stop)
+	p.wg.Wait()
+}
+
+// Wait waits for all workers to exit, which only happens on their own with
+// Options.Once
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
+
+func (p *Pool) Progress() Progress {
+	return Progress{
+		Rendered: atomic.LoadInt64(&p.rendered),
+		Retrying: atomic.LoadInt64(&p.retrying),
+		Failed:   atomic.LoadInt64(&p.failed),
+	}
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+
+	for {
+		// Keep going while there is work, otherwise wait
+		if job := db.ClaimRenderJob(p.options.Revisions); job != nil {
+			p.count(Process(job))
+
+			select {
+			case <-p.stop:
+				return
+			default:
+				continue
+			}
+		}
+
+		if p.options.Once {
+			return
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-wake:
+		case <-time.After(p.options.Interval):
+		}
+	}
+}
+
+// requeue periodically hands jobs of dead workers to the pool
+func (p *Pool) requeue() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			db.RequeueStaleRenderJobs(StaleTimeout, MaxAttempts)
+		}
+	}
+}
+
+func (p *Pool) count(job *db.RenderJob) {
+	switch job.Status {
+	case db.RenderJobDone:
+		atomic.AddInt64(&p.rendered, 1)
+	case db.RenderJobQueued:
+		atomic.AddInt64(&p.retrying, 1)
+	case db.RenderJobFailed:
+		atomic.AddInt64(&p.failed, 1)
+	}
+}
+
+// Process renders a claimed job and records the outcome on it
+func Process(job *db.RenderJob) *db.RenderJob {
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		heartbeat(job, stop)
+	}()
+
+	err := processJob(job)
+
+	close(stop)
+	<-stopped
+
+	var recorded bool
+
+	if err != nil {
+		fmt.Printf("[Render] Revision %d failed (attempt %d): %v\n", job.RevisionID, job.Attempts, err)
+		recorded = job.Fail(err, MaxAttempts, Backoff)
+	} else {
+		recorded = job.Complete()
+	}
+
+	if !recorded {
+		fmt.Printf("[Render] Revision %d was claimed again while rendering (attempt %d), outcome dropped\n", job.RevisionID, job.Attempts)
+	}
+
+	return job
+}
+
+// heartbeat keeps the job from being requeued as stale until stopped
+func heartbeat(job *db.RenderJob, stop chan struct{}) {
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if !job.Heartbeat() {
+				fmt.Printf("[Render] Revision %d lost its job (attempt %d)\n", job.RevisionID, job.Attempts)
+				return
+			}
+		}
+	}
+}
+
+func processJob(job *db.RenderJob) (err error) {
+	// A broken blueprint must not take the worker down
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	revision := db.GetRevisionById(job.RevisionID)
+
+	if revision == nil {
+		return errors.New("revision not found")
+	}
+
+	return Revision(revision)
+}
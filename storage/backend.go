@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Backend stores objects by key within a bucket
+type Backend interface {
+	// MakeBucket creates the bucket if it does not exist yet and makes it
+	// publicly readable
+	MakeBucket(bucket string) error
+
+	Put(bucket string, key string, reader io.Reader, contentType string) error
+
+	// Get returns ErrNotFound if there is no object with the key
+	Get(bucket string, key string) (io.ReadCloser, error)
+
+	Delete(bucket string, key string) error
+
+	// List returns the keys of all objects starting with the prefix
+	List(bucket string, prefix string) ([]string, error)
+
+	// URL the object is publicly available at
+	URL(bucket string, key string) string
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalBackend stores objects as files below a root directory, one
+// directory per bucket. Objects are served by Handler.
+type LocalBackend struct {
+	root      string
+	publicURL string
+}
+
+func NewLocalBackend(root string, publicURL string) *LocalBackend {
+	return &LocalBackend{
+		root:      root,
+		publicURL: publicURL,
+	}
+}
+
+func (b *LocalBackend) path(bucket string, key string) string {
+	// Clean against the root so keys can not escape the bucket
+	return filepath.Join(b.root, bucket, filepath.FromSlash(filepath.Clean("/"+key)))
+}
+
+func (b *LocalBackend) MakeBucket(bucket string) error {
+	return os.MkdirAll(filepath.Join(b.root, bucket), 0755)
+}
+
+func (b *LocalBackend) Put(bucket string, key string, reader io.Reader, contentType string) error {
+	path := b.path(bucket, key)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see partial objects
+	file, err := ioutil.TempFile(filepath.Dir(path), ".upload-")
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+func (b *LocalBackend) Get(bucket string, key string) (io.ReadCloser, error) {
+	file, err := os.Open(b.path(bucket, key))
+
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+
+	return file, err
+}
+
+func (b *LocalBackend) Delete(bucket string, key string) error {
+	err := os.Remove(b.path(bucket, key))
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (b *LocalBackend) List(bucket string, prefix string) ([]string, error) {
+	root := filepath.Join(b.root, bucket)
+
+	var keys []string
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".upload-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+
+	return keys, err
+}
+
+func (b *LocalBackend) URL(bucket string, key string) string {
+	return b.publicURL + "/" + bucket + "/" + key
+}
@@ -1,106 +1,268 @@
 package storage
 
 import (
-	"strconv"
-
-	"strings"
-
+	"bytes"
 	"fmt"
-	"os"
-
+	"io"
+	"mime"
 	"net/http"
+	"path"
+	"strconv"
+	"strings"
 
-	"bytes"
-
+	"github.com/BlooperDB/API/blueprint"
 	"github.com/BlooperDB/API/db"
-	"github.com/BlooperDB/API/utils"
-	"github.com/minio/minio-go"
-	"github.com/minio/minio-go/pkg/policy"
 )
 
-var PublicURL string
-
 var BlueprintStringBucket = "blooper-blueprints"
 var BlueprintRenderBucket = "blooper-blueprint-renders"
 
-var client *minio.Client
+var backend Backend
 
-func Initialize(minioClient *minio.Client, url string) {
-	client = minioClient
-	PublicURL = url
+func Initialize(b Backend) error {
+	backend = b
 
-	MakeBucket(BlueprintStringBucket)
-	MakeBucket(BlueprintRenderBucket)
+	if err := backend.MakeBucket(BlueprintStringBucket); err != nil {
+		return err
+	}
 
-	client.SetBucketPolicy(BlueprintStringBucket, "", policy.BucketPolicyReadOnly)
-	client.SetBucketPolicy(BlueprintRenderBucket, "", policy.BucketPolicyReadOnly)
+	return backend.MakeBucket(BlueprintRenderBucket)
 }
 
-func MakeBucket(name string) {
-	err := client.MakeBucket(name, "")
+// URL the object is publicly available at
+func URL(bucket string, key string) string {
+	return backend.URL(bucket, key)
+}
+
+// SaveRevision stores the string of the revision under its checksum, strings
+// shared with other revisions are only stored once. A string referenced
+// before is stored again unless it is there already, its first upload may
+// still be running or have failed.
+func SaveRevision(revision *db.Revision, blueprintString string) error {
+	if db.AcquireBlueprintString(revision.BlueprintChecksum) > 1 && exists(BlueprintStringBucket, revision.BlueprintChecksum) {
+		return nil
+	}
+
+	err := backend.Put(BlueprintStringBucket, revision.BlueprintChecksum, strings.NewReader(blueprintString), "text/plain")
+
 	if err != nil {
-		exists, err := client.BucketExists(name)
-		if err == nil && !exists {
-			fmt.Println("What?")
-			os.Exit(1)
-		} else if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
+		db.ReleaseBlueprintString(revision.BlueprintChecksum)
 	}
+
+	return err
 }
 
-func SaveRevision(revisionId uint, blueprintString string) {
-	reader := strings.NewReader(blueprintString)
-	client.PutObject(BlueprintStringBucket, RevisionToString(revisionId), reader, -1, minio.PutObjectOptions{
-		ContentType: "text/plain",
-	})
+// DeleteRevision drops the reference of the revision to its string and
+// removes the string once no revision references it anymore
+func DeleteRevision(revision *db.Revision) error {
+	if db.ReleaseBlueprintString(revision.BlueprintChecksum) > 0 {
+		return nil
+	}
+
+	return backend.Delete(BlueprintStringBucket, revision.BlueprintChecksum)
 }
 
-func GetRevision(revisionId uint) *string {
-	object, err := client.GetObject(BlueprintStringBucket, RevisionToString(revisionId), minio.GetObjectOptions{})
+func GetRevision(revision *db.Revision) *string {
+	object, err := backend.Get(BlueprintStringBucket, revision.BlueprintChecksum)
 
 	if err != nil {
 		return nil
 	}
 
+	defer object.Close()
+
 	buf := new(bytes.Buffer)
 	buf.ReadFrom(object)
 	s := buf.String()
 	return &s
 }
 
-func RevisionToString(revisionId uint) string {
-	return "revision-blueprint-" + strconv.FormatUint(uint64(revisionId), 10)
-}
+func GetRevisionBlueprint(revision *db.Revision) *blueprint.Container {
+	s := GetRevision(revision)
 
-func RenderAndSaveAndUpdateBlueprint(blueprintString string, revision *db.Revision) {
-	RenderAndSaveBlueprint(blueprintString)
-	revision.Rendered = true
-	revision.Save()
+	if s == nil {
+		return nil
+	}
+
+	container, err := blueprint.Decode(*s)
+
+	if err != nil {
+		return nil
+	}
+
+	return container
 }
 
-func RenderAndSaveBlueprint(blueprintString string) {
-	sha265 := utils.SHA265(blueprintString)
+// Strings used to be stored per revision as revision-blueprint-{id}
+const legacyRevisionPrefix = "revision-blueprint-"
 
-	// Normal
-	reader := strings.NewReader(blueprintString)
-	resp, _ := http.Post(os.Getenv("RENDERER_URL")+"/", "text/plain", reader)
-	client.PutObject(BlueprintRenderBucket, sha265+".png", resp.Body, -1, minio.PutObjectOptions{
-		ContentType: "image/png",
-	})
+// Marker stored once the strings have been migrated
+const revisionStringsMigrated = "migrated-revision-strings"
+
+// Advisory lock held while migrating
+const revisionStringsLock = 0x626c6f6f70657201
+
+// MigrateRevisionStrings moves strings stored per revision to their checksum.
+// It runs once, servers starting while another one migrates skip it.
+func MigrateRevisionStrings() error {
+	if exists(BlueprintStringBucket, revisionStringsMigrated) {
+		return nil
+	}
+
+	locked, err := db.WithLock(revisionStringsLock, func() error {
+		// Another server may have finished in the meantime
+		if exists(BlueprintStringBucket, revisionStringsMigrated) {
+			return nil
+		}
+
+		if err := migrateRevisionStrings(); err != nil {
+			return err
+		}
 
-	// Square
-	reader = strings.NewReader(blueprintString)
-	resp, _ = http.Post(os.Getenv("RENDERER_URL")+"/?square", "text/plain", reader)
-	client.PutObject(BlueprintRenderBucket, sha265+"-square.png", resp.Body, -1, minio.PutObjectOptions{
-		ContentType: "image/png",
+		return backend.Put(BlueprintStringBucket, revisionStringsMigrated, strings.NewReader(""), "text/plain")
 	})
 
-	// Thumbnail
-	reader = strings.NewReader(blueprintString)
-	resp, _ = http.Post(os.Getenv("RENDERER_URL")+"/?squarethumb", "text/plain", reader)
-	client.PutObject(BlueprintRenderBucket, sha265+"-thumbnail.png", resp.Body, -1, minio.PutObjectOptions{
-		ContentType: "image/png",
+	if err == nil && !locked {
+		fmt.Println("[Storage] Revision strings are migrated by another server")
+	}
+
+	return err
+}
+
+func migrateRevisionStrings() error {
+	keys, err := backend.List(BlueprintStringBucket, legacyRevisionPrefix)
+
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		id, err := strconv.ParseUint(strings.TrimPrefix(key, legacyRevisionPrefix), 10, 64)
+
+		if err != nil {
+			continue
+		}
+
+		revision := db.GetRevisionByIdUnscoped(uint(id))
+
+		// Deleted revisions hold no reference, their strings are left as
+		// they are
+		if revision != nil && revision.DeletedAt != nil {
+			continue
+		}
+
+		if revision != nil {
+			if err := copyObject(BlueprintStringBucket, key, revision.BlueprintChecksum, "text/plain"); err != nil {
+				return err
+			}
+		}
+
+		if err := backend.Delete(BlueprintStringBucket, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyObject(bucket string, from string, to string, contentType string) error {
+	if exists(bucket, to) {
+		return nil
+	}
+
+	object, err := backend.Get(bucket, from)
+
+	if err != nil {
+		return err
+	}
+
+	defer object.Close()
+
+	return backend.Put(bucket, to, object, contentType)
+}
+
+func exists(bucket string, key string) bool {
+	object, err := backend.Get(bucket, key)
+
+	if err != nil {
+		return false
+	}
+
+	object.Close()
+
+	return true
+}
+
+// SaveRender stores a rendered image of a blueprint string
+func SaveRender(key string, reader io.Reader, contentType string) error {
+	return backend.Put(BlueprintRenderBucket, key, reader, contentType)
+}
+
+// GetRender returns ErrNotFound if there is no render with the key
+func GetRender(key string) ([]byte, error) {
+	object, err := backend.Get(BlueprintRenderBucket, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer object.Close()
+
+	buf := new(bytes.Buffer)
+
+	if _, err := buf.ReadFrom(object); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// DeleteRenders removes all renders with keys starting with the prefix
+func DeleteRenders(prefix string) error {
+	keys, err := backend.List(BlueprintRenderBucket, prefix)
+
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := backend.Delete(BlueprintRenderBucket, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Handler serves objects of the storage backend as /{bucket}/{key}, for
+// backends that are not reachable on their own
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+
+		if len(parts) != 2 || (parts[0] != BlueprintStringBucket && parts[0] != BlueprintRenderBucket) {
+			http.NotFound(w, r)
+			return
+		}
+
+		object, err := backend.Get(parts[0], parts[1])
+
+		if err == ErrNotFound {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		defer object.Close()
+
+		if contentType := mime.TypeByExtension(path.Ext(parts[1])); contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		} else {
+			w.Header().Set("Content-Type", "text/plain")
+		}
+
+		io.Copy(w, object)
 	})
 }
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryBackend keeps all objects in memory, everything is lost on restart
+type MemoryBackend struct {
+	mutex     sync.RWMutex
+	buckets   map[string]map[string][]byte
+	publicURL string
+}
+
+func NewMemoryBackend(publicURL string) *MemoryBackend {
+	return &MemoryBackend{
+		buckets:   make(map[string]map[string][]byte),
+		publicURL: publicURL,
+	}
+}
+
+func (b *MemoryBackend) MakeBucket(bucket string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, ok := b.buckets[bucket]; !ok {
+		b.buckets[bucket] = make(map[string][]byte)
+	}
+
+	return nil
+}
+
+func (b *MemoryBackend) Put(bucket string, key string, reader io.Reader, contentType string) error {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	objects, ok := b.buckets[bucket]
+
+	if !ok {
+		return ErrNotFound
+	}
+
+	objects[key] = data
+
+	return nil
+}
+
+func (b *MemoryBackend) Get(bucket string, key string) (io.ReadCloser, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	data, ok := b.buckets[bucket][key]
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (b *MemoryBackend) Delete(bucket string, key string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.buckets[bucket], key)
+
+	return nil
+}
+
+func (b *MemoryBackend) List(bucket string, prefix string) ([]string, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	var keys []string
+
+	for key := range b.buckets[bucket] {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+func (b *MemoryBackend) URL(bucket string, key string) string {
+	return b.publicURL + "/" + bucket + "/" + key
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"errors"
+	"io"
+
+	"github.com/minio/minio-go"
+	"github.com/minio/minio-go/pkg/policy"
+)
+
+type MinioBackend struct {
+	client    *minio.Client
+	publicURL string
+}
+
+func NewMinioBackend(client *minio.Client, publicURL string) *MinioBackend {
+	return &MinioBackend{
+		client:    client,
+		publicURL: publicURL,
+	}
+}
+
+func (b *MinioBackend) MakeBucket(bucket string) error {
+	if err := b.client.MakeBucket(bucket, ""); err != nil {
+		exists, err := b.client.BucketExists(bucket)
+
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return errors.New("unable to create bucket " + bucket)
+		}
+	}
+
+	return b.client.SetBucketPolicy(bucket, "", policy.BucketPolicyReadOnly)
+}
+
+func (b *MinioBackend) Put(bucket string, key string, reader io.Reader, contentType string) error {
+	_, err := b.client.PutObject(bucket, key, reader, -1, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	return err
+}
+
+func (b *MinioBackend) Get(bucket string, key string) (io.ReadCloser, error) {
+	object, err := b.client.GetObject(bucket, key, minio.GetObjectOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Objects are fetched lazily, stat to find out whether it exists
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return object, nil
+}
+
+func (b *MinioBackend) Delete(bucket string, key string) error {
+	return b.client.RemoveObject(bucket, key)
+}
+
+func (b *MinioBackend) List(bucket string, prefix string) ([]string, error) {
+	done := make(chan struct{})
+	defer close(done)
+
+	var keys []string
+
+	for object := range b.client.ListObjects(bucket, prefix, true, done) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+
+		keys = append(keys, object.Key)
+	}
+
+	return keys, nil
+}
+
+func (b *MinioBackend) URL(bucket string, key string) string {
+	return b.publicURL + "/" + bucket + "/" + key
+}
@@ -34,21 +34,44 @@ var (
 
 var (
 	Error_blueprint_not_found = ErrorResponse{200, "Blueprint not found", 404}
+	Error_blueprint_own_fork  = ErrorResponse{201, "Unable to fork your own blueprint", 400}
 )
 
 var (
 	Error_revision_not_found              = ErrorResponse{300, "Blueprint revision not found", 404}
 	Error_blueprint_string_already_exists = ErrorResponse{301, "Blueprint string already exists", 400}
+	Error_revision_not_book               = ErrorResponse{302, "Blueprint revision is not a blueprint book", 400}
+	Error_revision_child_not_found        = ErrorResponse{303, "Blueprint book child not found", 404}
+	Error_revision_not_blueprint          = ErrorResponse{304, "Blueprint revision is not a blueprint", 400}
 )
 
 var (
 	Error_comment_not_found = ErrorResponse{400, "Blueprint comment not found", 404}
+	Error_comment_too_deep  = ErrorResponse{401, "Comment replies are nested too deep", 400}
 )
 
 var (
 	Error_no_search_terms = ErrorResponse{500, "No search terms given", 400}
+	Error_invalid_cursor  = ErrorResponse{501, "Invalid cursor", 400}
+	Error_invalid_order   = ErrorResponse{502, "Invalid order", 400}
 )
 
 var (
 	Error_rating_not_found = ErrorResponse{600, "Rating not found", 404}
 )
+
+var (
+	Error_render_not_found         = ErrorResponse{700, "Render not found", 404}
+	Error_unsupported_image_format = ErrorResponse{701, "Unsupported image format", 400}
+	Error_invalid_image_size       = ErrorResponse{702, "Invalid image size", 400}
+	Error_blueprint_too_large      = ErrorResponse{703, "Blueprint too large to draw", 400}
+)
+
+var (
+	Error_collection_not_found = ErrorResponse{800, "Collection not found", 404}
+	Error_collection_empty     = ErrorResponse{801, "Collection has no blueprints to export", 400}
+)
+
+var (
+	Error_favorite_not_found = ErrorResponse{900, "Favorite not found", 404}
+)
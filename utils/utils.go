@@ -5,18 +5,12 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"encoding/base64"
 	"reflect"
 
-	"errors"
-
-	"bytes"
-	"compress/zlib"
-	"io"
-
 	"crypto/sha256"
 	"fmt"
 
+	"github.com/BlooperDB/API/blueprint"
 	"github.com/graphql-go/graphql"
 	"gopkg.in/validator.v2"
 )
@@ -93,36 +87,8 @@ func ValidateRequestBody(r *http.Request, s interface{}) *ErrorResponse {
 }
 
 func validBlueprintString(v interface{}, _ string) error {
-	s := reflect.ValueOf(v).String()
-
-	if len(s) == 0 {
-		return errors.New("Not valid blueprint string")
-	}
-
-	decoded, err := base64.StdEncoding.DecodeString(s[1:])
-	if err != nil {
-		return errors.New("Not valid blueprint string")
-	}
-
-	b := bytes.NewReader(decoded)
-	r, err := zlib.NewReader(b)
-
-	if err != nil {
-		return errors.New("Not valid blueprint string")
-	}
-
-	var out bytes.Buffer
-	io.Copy(&out, r)
-	r.Close()
-
-	var js map[string]interface{}
-	err = json.Unmarshal([]byte(out.String()), &js)
-
-	if err != nil {
-		return errors.New("Not valid blueprint string")
-	}
-
-	return nil
+	_, err := blueprint.Decode(reflect.ValueOf(v).String())
+	return err
 }
 
 func SHA265(s string) string {